@@ -1,14 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -21,23 +27,391 @@ type ApiResponse struct {
 	IsNew  bool   `json:"isNew"`
 }
 
-const dbName = "./items.db"
 const apiURL = "https://neal.fun/api/infinite-craft/pair"
 
-var localItemsCache map[string]string
+// dbName is the database the crawl writes to, -shard picks a separate file.
+var dbName = "./items.db"
+
+// localItemsCache maps every known item to its emoji. It is shared by the
+// exploration loop and API result handling, so access goes through
+// localItemsMu.
+var (
+	localItemsCache map[string]string
+	localItemsMu    sync.RWMutex
+)
+
+type pair struct {
+	first, second string
+}
+
+// attemptedPairs holds every pair already recorded in the combinations
+// table, so resuming a crawl doesn't query the database for each candidate.
+var (
+	attemptedPairs   map[pair]bool
+	attemptedPairsMu sync.RWMutex
+)
+
+// dryRun makes the exploration loop only log the pairs it would try, without
+// calling the API or writing to the database.
+var dryRun bool
+
+// allowSelf lets getRandomItems pick the same item twice, A + A is a valid
+// combination in the game but is skipped by default.
+var allowSelf bool
+
+// target limits the exploration to combinations of this item with the others,
+// used to fill in the gaps around a single item.
+var target string
+
+var throttle *adaptiveThrottle
+
+// maxDepth keeps items deeper than this out of the candidate pairs, 0 means
+// no limit. depths is refreshed while crawling by refreshDepths.
+var (
+	maxDepth int
+	depths   map[string]int
+	depthsMu sync.RWMutex
+)
+
+// depthRefreshInterval is how often depths are recomputed with -max-depth.
+const depthRefreshInterval = time.Minute
+
+// sessionItems holds the items first discovered during this run, guarded by
+// localItemsMu. With -prefer-session one side of a pair is drawn from them
+// that share of the time, so the crawl builds on new territory.
+var (
+	sessionItems  = make(map[string]bool)
+	preferSession float64
+)
+
+// minSessionItems is how many session items -prefer-session needs before it
+// biases the choice, with fewer it would retry the same few pairs.
+const minSessionItems = 10
+
+// maxItems stops the crawl once this many items are known, 0 means no limit.
+var maxItems int
+
+// auditDB stores the raw API responses when -audit is set, it is nil
+// otherwise.
+var auditDB *sql.DB
+
+// inflight bounds the number of concurrent API requests, independent of the
+// rate the throttle allows. It is nil when unbounded.
+var inflight chan struct{}
+
+// crawlMetrics counts what happened during a crawl.
+type crawlMetrics struct {
+	APICalls     atomic.Int64
+	NewItems     atomic.Int64
+	Combinations atomic.Int64
+	RateLimited  atomic.Int64
+	// Errors counts the failures the crawl carries on after, the others
+	// end it
+	Errors atomic.Int64
+}
+
+var metrics crawlMetrics
+
+func (m *crawlMetrics) snapshot() map[string]int64 {
+	return map[string]int64{
+		"apiCalls":     m.APICalls.Load(),
+		"newItems":     m.NewItems.Load(),
+		"combinations": m.Combinations.Load(),
+		"rateLimited":  m.RateLimited.Load(),
+		"errors":       m.Errors.Load(),
+	}
+}
+
+func (m *crawlMetrics) logSummary() {
+	logrus.Infof("API calls: %d, new items: %d, combinations recorded: %d, rate limited: %d, errors: %d",
+		m.APICalls.Load(), m.NewItems.Load(), m.Combinations.Load(), m.RateLimited.Load(), m.Errors.Load())
+}
+
+// serveMetrics exposes the crawl metrics as JSON while the crawl runs.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(metrics.snapshot())
+	})
+	logrus.Info("Serving crawl metrics on ", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		logrus.Error("Metrics server stopped: ", err)
+	}
+}
+
+// adaptiveThrottle paces API calls AIMD-style: the rate is halved on every
+// 429 and grows by one request per second after each stretch of
+// throttleRecoverAfter calls without one, bounded by minRate and maxRate.
+// An unpaced throttle never waits, which keeps tests against a mock API fast.
+// Independent of the rate, it can also pause for burstPause after every
+// burstSize calls.
+type adaptiveThrottle struct {
+	mu         sync.Mutex
+	rate       float64 // requests per second
+	minRate    float64
+	maxRate    float64
+	successes  int
+	unpaced    bool
+	burstSize  int
+	burstPause time.Duration
+	calls      int
+}
+
+const throttleRecoverAfter = 100
+
+// newAdaptiveThrottle starts pacing at rate requests per second, a rate of 0
+// disables pacing entirely.
+func newAdaptiveThrottle(rate, minRate, maxRate float64) *adaptiveThrottle {
+	if rate == 0 {
+		return &adaptiveThrottle{unpaced: true}
+	}
+	rate = math.Max(minRate, math.Min(rate, maxRate))
+	return &adaptiveThrottle{rate: rate, minRate: minRate, maxRate: maxRate}
+}
+
+func (t *adaptiveThrottle) wait() {
+	if t.unpaced {
+		return
+	}
+	t.mu.Lock()
+	delay := time.Duration(float64(time.Second) / t.rate)
+	t.mu.Unlock()
+	time.Sleep(delay)
+}
+
+// afterCall counts a completed API call and takes the pause between bursts
+// once burstSize calls were made, 0 disables the pauses.
+func (t *adaptiveThrottle) afterCall() {
+	if t.burstSize <= 0 {
+		return
+	}
+	t.mu.Lock()
+	t.calls++
+	pause := t.calls%t.burstSize == 0
+	t.mu.Unlock()
+	if pause {
+		logrus.Infof("Made %d calls, pausing for %s", t.burstSize, t.burstPause)
+		time.Sleep(t.burstPause)
+	}
+}
+
+func (t *adaptiveThrottle) onRateLimited() {
+	if t.unpaced {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.rate = math.Max(t.minRate, t.rate/2)
+	t.successes = 0
+	logrus.Warnf("Rate limited, slowing down to %.2f requests/s", t.rate)
+}
+
+func (t *adaptiveThrottle) onSuccess() {
+	if t.unpaced {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.successes++
+	if t.successes < throttleRecoverAfter || t.rate >= t.maxRate {
+		return
+	}
+	t.successes = 0
+	t.rate = math.Min(t.maxRate, t.rate+1)
+	logrus.Debugf("No rate limits for a while, speeding up to %.2f requests/s", t.rate)
+}
 
 func main() {
+	maxCombinations := flag.Int("max-combinations", 500000, "stop after this many new combinations")
+	maxAttempts := flag.Int("max-attempts", 0, "stop after this many attempts (default 5x max-combinations)")
+	flag.BoolVar(&allowSelf, "allow-self", false, "also try combining an item with itself")
+	flag.StringVar(&target, "target", "", "only try combinations of this item with the others")
+	flag.BoolVar(&dryRun, "dry-run", false, "log candidate pairs without calling the API or writing to the database")
+	duration := flag.Duration("duration", 0, "stop the crawl after this long, e.g. 2h (0 runs until the budget is used up)")
+	rate := flag.Float64("rate", 20, "initial API requests per second, 0 disables the delay between requests")
+	minRate := flag.Float64("min-rate", 0.5, "lowest API requests per second after rate limiting")
+	maxRate := flag.Float64("max-rate", 20, "highest API requests per second when recovering")
+	burstSize := flag.Int("burst-size", 0, "pause after every this many API calls, 0 never pauses")
+	burstPause := flag.Duration("burst-pause", 30*time.Second, "length of the pause after each burst-size calls")
+	maxInflight := flag.Int("max-inflight", 4, "most API requests in flight at once, 0 for no limit")
+	flag.IntVar(&maxDepth, "max-depth", 0, "only combine items at most this many steps from the base elements, items of unknown depth are always used (0 for no limit)")
+	flag.IntVar(&maxItems, "max-items", 0, "stop once this many items are known (0 for no limit)")
+	flag.Float64Var(&preferSession, "prefer-session", 0, "share of pairs, 0 to 1, that use an item discovered during this run once there are enough of them")
+	audit := flag.Bool("audit", false, "store the raw API responses in the apiResponses table")
+	seed := flag.Int64("seed", 0, "seed for picking the pairs to try, the same seed repeats a crawl (0 picks one from the time)")
+	logFile := flag.String("log-file", "", "write logs to this file instead of stderr, rotating it by size")
+	logMaxSize := flag.Int64("log-max-size", 100, "rotate the log file once it reaches this many megabytes")
+	logBackups := flag.Int("log-backups", 3, "number of rotated log files to keep")
+	shard := flag.Int("shard", 0, "write to items.shard-<n>.db instead of items.db, so several collectors don't contend for one database. Each shard starts from the base elements, merge them into items.db afterwards with go run mergeShards.go items.shard-*.db (0 uses items.db)")
+	bootstrap := flag.String("bootstrap", "", "load the items and combinations of this dataset file before crawling, see bootstrapDataset")
+	statusAddr := flag.String("status-addr", "", "serve crawl metrics as JSON on this address, e.g. :9090")
+	flag.Parse()
+
+	if *minRate <= 0 || *maxRate < *minRate {
+		logrus.Fatal("Invalid rate bounds, need 0 < min-rate <= max-rate")
+	}
+	throttle = newAdaptiveThrottle(*rate, *minRate, *maxRate)
+	if *burstSize < 0 || *burstPause < 0 {
+		logrus.Fatal("Invalid burst, need a burst-size and burst-pause of 0 or more")
+	}
+	throttle.burstSize, throttle.burstPause = *burstSize, *burstPause
+	if *maxInflight < 0 {
+		logrus.Fatal("Invalid max-inflight, need 0 or more")
+	} else if *maxInflight > 0 {
+		inflight = make(chan struct{}, *maxInflight)
+	}
+
+	if preferSession < 0 || preferSession > 1 {
+		logrus.Fatal("Invalid prefer-session, need a share between 0 and 1")
+	}
+
+	if *shard < 0 {
+		logrus.Fatal("Invalid shard, need 0 or more")
+	} else if *shard > 0 {
+		dbName = fmt.Sprintf("./items.shard-%d.db", *shard)
+	}
+
+	if *maxAttempts == 0 {
+		*maxAttempts = *maxCombinations * 5
+	}
+
 	logrus.SetLevel(logrus.DebugLevel)
+	if *logFile != "" {
+		if *logMaxSize <= 0 {
+			logrus.Fatal("Invalid log-max-size, need a positive size")
+		}
+		w, err := openRotatingFile(*logFile, *logMaxSize<<20, *logBackups)
+		if err != nil {
+			logrus.Fatal("Failed to open log file: ", err)
+		}
+		defer w.Close()
+		logrus.SetOutput(w)
+	}
 	db := initializeDatabase()
 	defer db.Close()
 
+	if *bootstrap != "" {
+		if dryRun {
+			logrus.Fatal("bootstrap writes to the database and can't be combined with dry-run")
+		}
+		bootstrapDataset(db, *bootstrap)
+	}
+
 	initializeLocalCache(db)
+	initializeAttemptedPairs(db)
+
+	if *audit {
+		createAuditTable(db)
+		auditDB = db
+	}
+
+	if maxDepth > 0 {
+		refreshDepths(db)
+		go func() {
+			for range time.Tick(depthRefreshInterval) {
+				refreshDepths(db)
+			}
+		}()
+	}
+
+	if _, ok := localItemsCache[target]; target != "" && !ok {
+		logrus.Fatal("Unknown target item: ", target)
+	}
+
+	if *statusAddr != "" {
+		go serveMetrics(*statusAddr)
+	}
+
+	ctx := context.Background()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	logrus.Info("Using seed ", *seed)
+	rng := rand.New(rand.NewSource(*seed))
+
+	exploreCombinations(ctx, db, rng, *maxCombinations, *maxAttempts)
+}
+
+// rotatingFile is a log file that is moved to path.1 once it reaches
+// maxSize bytes, shifting older files up to path.<backups>.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	maxSize int64
+	backups int
+	file    *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, maxSize int64, backups int) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if r.backups > 0 {
+		// The oldest backup is overwritten by the rename
+		for i := r.backups - 1; i > 0; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Remove(r.path); err != nil {
+		return err
+	}
+	return r.open()
+}
 
-	N := 500000
-	exploreCombinations(db, N, N*5)
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
 }
 
 func initializeLocalCache(db *sql.DB) {
+	localItemsMu.Lock()
+	defer localItemsMu.Unlock()
 	localItemsCache = make(map[string]string)
 	rows, err := db.Query("SELECT name, emoji FROM items")
 	if err != nil {
@@ -54,11 +428,32 @@ func initializeLocalCache(db *sql.DB) {
 	logrus.Info("Local cache initialized with items from database")
 }
 
+func initializeAttemptedPairs(db *sql.DB) {
+	attemptedPairsMu.Lock()
+	defer attemptedPairsMu.Unlock()
+	attemptedPairs = make(map[pair]bool)
+	rows, err := db.Query("SELECT firstItem, secondItem FROM combinations")
+	if err != nil {
+		logrus.Fatal("Failed to load attempted pairs: ", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p pair
+		if err := rows.Scan(&p.first, &p.second); err != nil {
+			logrus.Fatal("Failed to read attempted pair: ", err)
+		}
+		attemptedPairs[p] = true
+	}
+	logrus.Info("Loaded ", len(attemptedPairs), " attempted pairs from database")
+}
+
 func initializeDatabase() *sql.DB {
 	dbExists := checkDatabaseExists()
 
 	logrus.Debug("Database exists: ", dbExists)
-	db, err := sql.Open("sqlite3", dbName)
+	// Enforce the foreign keys on every connection so no new orphaned
+	// combinations can be written
+	db, err := sql.Open("sqlite3", dbName+"?_foreign_keys=on")
 	if err != nil {
 		logrus.Fatal("Failed to open database: ", err)
 	}
@@ -67,9 +462,87 @@ func initializeDatabase() *sql.DB {
 		createTables(db)
 		insertInitialItems(db)
 	}
+	migrateCreatedAt(db)
 	return db
 }
 
+// dataset is the file read by -bootstrap: the localStorage.json written by
+// json.go, optionally with the combinations in the format of its jsonl export.
+type dataset struct {
+	Elements []struct {
+		Text       string `json:"text"`
+		Emoji      string `json:"emoji"`
+		Discovered bool   `json:"discovered"`
+	} `json:"elements"`
+	Combinations []struct {
+		First  string `json:"first"`
+		Second string `json:"second"`
+		Result string `json:"result"`
+	} `json:"combinations"`
+}
+
+// bootstrapDataset upserts the items and combinations of a dataset file, so a
+// crawl can start from a larger graph than the base elements. Combinations
+// already recorded keep their result, ones referencing unknown items are
+// skipped.
+func bootstrapDataset(db *sql.DB, path string) {
+	f, err := os.Open(path)
+	if err != nil {
+		logrus.Fatal("Failed to open bootstrap dataset: ", err)
+	}
+	defer f.Close()
+	var data dataset
+	if err = json.NewDecoder(f).Decode(&data); err != nil {
+		logrus.Fatal("Failed to read bootstrap dataset: ", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		logrus.Fatal("Failed to start bootstrap: ", err)
+	}
+	defer tx.Rollback()
+	for _, item := range data.Elements {
+		_, err = tx.Exec("INSERT INTO items (name, emoji, isNew) VALUES (?, ?, ?) ON CONFLICT(name) DO UPDATE SET emoji=excluded.emoji, isNew=(isNew OR excluded.isNew)", item.Text, item.Emoji, item.Discovered)
+		if err != nil {
+			logrus.Fatal("Failed to bootstrap item: ", err)
+		}
+	}
+	added, skipped := 0, 0
+	for _, c := range data.Combinations {
+		// Timestamps stay NULL, these weren't found by this crawl
+		res, err := tx.Exec("INSERT INTO combinations (firstItem, secondItem, resultItem) VALUES (?, ?, ?) ON CONFLICT(firstItem, secondItem) DO NOTHING", c.First, c.Second, c.Result)
+		if err != nil {
+			logrus.Warnf("Skipping bootstrap combination %s + %s = %s: %v", c.First, c.Second, c.Result, err)
+			skipped++
+			continue
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			added++
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		logrus.Fatal("Failed to commit bootstrap: ", err)
+	}
+	logrus.Infof("Bootstrapped %d items and %d new combinations from %s, skipped %d", len(data.Elements), added, path, skipped)
+}
+
+// migrateCreatedAt adds the createdAt column to databases from before it
+// existed. Combinations recorded until then keep a NULL timestamp.
+func migrateCreatedAt(db *sql.DB) {
+	var exists bool
+	err := db.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('combinations') WHERE name = 'createdAt'").Scan(&exists)
+	if err != nil {
+		logrus.Fatal("Failed to check combinations table: ", err)
+	}
+	if exists {
+		return
+	}
+	if _, err = db.Exec("ALTER TABLE combinations ADD COLUMN createdAt TIMESTAMP"); err != nil {
+		logrus.Fatal("Failed to add createdAt column: ", err)
+	}
+	logrus.Info("Added createdAt column to combinations table")
+}
+
 func checkDatabaseExists() bool {
 	if _, err := os.Stat(dbName); err != nil {
 		return !os.IsNotExist(err)
@@ -91,6 +564,7 @@ func createTables(db *sql.DB) {
         firstItem TEXT NOT NULL,
         secondItem TEXT NOT NULL,
         resultItem TEXT NOT NULL,
+        createdAt TIMESTAMP,
         UNIQUE(firstItem, secondItem),
         FOREIGN KEY (firstItem) REFERENCES items(name),
         FOREIGN KEY (secondItem) REFERENCES items(name),
@@ -139,6 +613,7 @@ func combineElements(first, second string, db *sql.DB) {
 
 	insertOrUpdateItem(response.Result, response.Emoji, response.IsNew, db)
 	insertCombination(first, second, response.Result, db)
+	throttle.afterCall()
 }
 
 func callApi(first, second string) (*ApiResponse, error) {
@@ -157,14 +632,23 @@ func callApi(first, second string) (*ApiResponse, error) {
 	req.Header.Add("user-agent", "InfiniteCraft_Mapper/rate-limited")
 
 	logrus.Debug("Calling API with URL: ", req.URL.String())
+	metrics.APICalls.Add(1)
 
+	if inflight != nil {
+		inflight <- struct{}{}
+	}
 	resp, err := client.Do(req)
 	if err != nil {
+		releaseInflight()
 		return nil, err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode == http.StatusTooManyRequests {
+		// Don't hold a slot while backing off
+		releaseInflight()
+		metrics.RateLimited.Add(1)
+		throttle.onRateLimited()
 		retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
 		if err != nil {
 			retryAfter = 60 // Default to 60 seconds if not parseable
@@ -172,13 +656,19 @@ func callApi(first, second string) (*ApiResponse, error) {
 		time.Sleep(time.Duration(retryAfter+1) * time.Second)
 		return callApi(first, second) // Recursively retry the request
 	} else if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		releaseInflight()
+		auditResponse(first, second, resp.StatusCode, body)
 		panic(fmt.Sprintf("API request failed with status code: %d", resp.StatusCode))
 	}
+	throttle.onSuccess()
 
 	body, err := io.ReadAll(resp.Body)
+	releaseInflight()
 	if err != nil {
 		return nil, err
 	}
+	auditResponse(first, second, resp.StatusCode, body)
 
 	var response ApiResponse
 	if err := json.Unmarshal(body, &response); err != nil {
@@ -188,10 +678,57 @@ func callApi(first, second string) (*ApiResponse, error) {
 	return &response, nil
 }
 
+// createAuditTable creates the table -audit writes to. Pairs are stored in
+// canonical order, so A + B and B + A share a row.
+func createAuditTable(db *sql.DB) {
+	_, err := db.Exec(`CREATE TABLE IF NOT EXISTS apiResponses (
+        firstItem TEXT NOT NULL,
+        secondItem TEXT NOT NULL,
+        status INTEGER NOT NULL,
+        body TEXT NOT NULL,
+        createdAt TIMESTAMP NOT NULL,
+        PRIMARY KEY (firstItem, secondItem)
+    )`)
+	if err != nil {
+		logrus.Fatal("Failed to create apiResponses table: ", err)
+	}
+}
+
+// auditResponse records a raw API response when -audit is set, keeping the
+// latest one for each pair.
+func auditResponse(first, second string, status int, body []byte) {
+	if auditDB == nil {
+		return
+	}
+	if second < first {
+		first, second = second, first
+	}
+	_, err := auditDB.Exec("INSERT OR REPLACE INTO apiResponses (firstItem, secondItem, status, body, createdAt) VALUES (?, ?, ?, ?, ?)",
+		first, second, status, string(body), time.Now().UTC())
+	if err != nil {
+		metrics.Errors.Add(1)
+		logrus.Error("Failed to store API response: ", err)
+	}
+}
+
+func releaseInflight() {
+	if inflight != nil {
+		<-inflight
+	}
+}
+
 func insertOrUpdateItem(name, emoji string, isNew bool, db *sql.DB) {
 	logrus.Debugf("Inserting or updating item: %s, %s, %t", name, emoji, isNew)
+	localItemsMu.Lock()
+	if _, known := localItemsCache[name]; !known {
+		metrics.NewItems.Add(1)
+		sessionItems[name] = true
+	}
 	localItemsCache[name] = emoji // Update local cache
-	_, err := db.Exec("INSERT INTO items (name, emoji, isNew) VALUES (?, ?, ?) ON CONFLICT(name) DO UPDATE SET emoji=excluded.emoji, isNew=excluded.isNew", name, emoji, isNew)
+	localItemsMu.Unlock()
+	// isNew records whether the item was ever a first discovery, so a later
+	// response for the same item must not reset it
+	_, err := db.Exec("INSERT INTO items (name, emoji, isNew) VALUES (?, ?, ?) ON CONFLICT(name) DO UPDATE SET emoji=excluded.emoji, isNew=(isNew OR excluded.isNew)", name, emoji, isNew)
 	if err != nil {
 		logrus.Fatal("Failed to insert or update item: ", err)
 	}
@@ -199,69 +736,144 @@ func insertOrUpdateItem(name, emoji string, isNew bool, db *sql.DB) {
 
 func insertCombination(firstItem, secondItem, resultItem string, db *sql.DB) {
 	logrus.Debugf("Inserting combination: %s, %s, %s", firstItem, secondItem, resultItem)
-	_, err := db.Exec("INSERT INTO combinations (firstItem, secondItem, resultItem) VALUES (?, ?, ?)", firstItem, secondItem, resultItem)
+	attemptedPairsMu.Lock()
+	attemptedPairs[pair{firstItem, secondItem}] = true // Update attempted pairs
+	attemptedPairsMu.Unlock()
+	_, err := db.Exec("INSERT INTO combinations (firstItem, secondItem, resultItem, createdAt) VALUES (?, ?, ?, ?)", firstItem, secondItem, resultItem, time.Now().UTC())
 	if err != nil {
 		logrus.Fatal("Failed to insert combination: ", err)
 	}
+	metrics.Combinations.Add(1)
 }
 
-func getRandomItems() (string, string, error) {
-	var items []string
+func getRandomItems(rng *rand.Rand) (string, string, error) {
+	// Pick from a snapshot, so items added meanwhile don't affect the choice
+	localItemsMu.RLock()
+	depthsMu.RLock()
+	items := make([]string, 0, len(localItemsCache))
+	var session []string
 	for item := range localItemsCache {
+		if d, known := depths[item]; maxDepth > 0 && known && d > maxDepth {
+			continue
+		}
 		items = append(items, item)
+		if sessionItems[item] {
+			session = append(session, item)
+		}
 	}
-
-	if len(items) < 2 {
+	depthsMu.RUnlock()
+	localItemsMu.RUnlock()
+	// Map order is random on its own, sorting keeps seeded crawls
+	// reproducible
+	sort.Strings(items)
+	sort.Strings(session)
+
+	if len(items) == 0 || (len(items) < 2 && !allowSelf) {
 		return "", "", fmt.Errorf("not enough items to combine")
 	}
 
-	firstIndex := rand.Intn(len(items))
-	secondIndex := firstIndex
-	for secondIndex == firstIndex {
-		secondIndex = rand.Intn(len(items))
+	// Until the run has found enough items of its own, the full pool is used
+	pool := items
+	if preferSession > 0 && len(session) >= minSessionItems && rng.Float64() < preferSession {
+		pool = session
 	}
 
-	return items[firstIndex], items[secondIndex], nil
+	if target != "" {
+		second := pool[rng.Intn(len(pool))]
+		for second == target && !allowSelf {
+			second = pool[rng.Intn(len(pool))]
+		}
+		return target, second, nil
+	}
+
+	first := pool[rng.Intn(len(pool))]
+	second := items[rng.Intn(len(items))]
+	for second == first && !allowSelf {
+		second = items[rng.Intn(len(items))]
+	}
+	return first, second, nil
 }
 
-// Function to check if a combination has already been attempted
-func combinationExists(firstItem, secondItem string, db *sql.DB) (bool, error) {
-	query := `SELECT COUNT(*) FROM combinations WHERE firstItem = ? AND secondItem = ?`
-	var count int
-	err := db.QueryRow(query, firstItem, secondItem).Scan(&count)
+// refreshDepths recomputes the depth of every item from the recorded
+// combinations.
+func refreshDepths(db *sql.DB) {
+	rows, err := db.Query("SELECT firstItem, secondItem, resultItem FROM combinations")
 	if err != nil {
-		return false, err
+		metrics.Errors.Add(1)
+		logrus.Error("Failed to load combinations for depths: ", err)
+		return
 	}
-	return count > 0, nil
+	defer rows.Close()
+
+	var recipes []recipe
+	for rows.Next() {
+		var r recipe
+		if err := rows.Scan(&r.first, &r.second, &r.result); err != nil {
+			metrics.Errors.Add(1)
+			logrus.Error("Failed to read combination for depths: ", err)
+			return
+		}
+		recipes = append(recipes, r)
+	}
+
+	depth, _ := computeDepths(recipes)
+
+	depthsMu.Lock()
+	depths = depth
+	depthsMu.Unlock()
+	logrus.Debug("Recomputed depths of ", len(depth), " items")
 }
 
-// Main exploration function to randomly try new combinations
-func exploreCombinations(db *sql.DB, maxCombinations, maxAttempts int) {
+// Function to check if a combination has already been attempted
+func combinationExists(firstItem, secondItem string) bool {
+	attemptedPairsMu.RLock()
+	defer attemptedPairsMu.RUnlock()
+	return attemptedPairs[pair{firstItem, secondItem}]
+}
+
+// Main exploration function to randomly try new combinations, stops early
+// once ctx is done
+func exploreCombinations(ctx context.Context, db *sql.DB, rng *rand.Rand, maxCombinations, maxAttempts int) {
 	attempts := 0
 	createdCombinations := 0
 
 	for createdCombinations < maxCombinations && attempts < maxAttempts {
-		firstItem, secondItem, err := getRandomItems()
-		if err != nil {
-			logrus.Error("Error getting random items: ", err)
-			return
+		if ctx.Err() != nil {
+			logrus.Info("Stopping exploration: ", ctx.Err())
+			break
+		}
+
+		// The local cache holds every known item, so it doubles as the count
+		localItemsMu.RLock()
+		items := len(localItemsCache)
+		localItemsMu.RUnlock()
+		if maxItems > 0 && items >= maxItems {
+			logrus.Info("Stopping exploration: reached ", items, " items")
+			break
 		}
 
-		exists, err := combinationExists(firstItem, secondItem, db)
+		firstItem, secondItem, err := getRandomItems(rng)
 		if err != nil {
-			logrus.Error("Error checking if combination exists: ", err)
+			logrus.Error("Error getting random items: ", err)
 			return
 		}
 
-		if !exists {
-			combineElements(firstItem, secondItem, db)
+		if !combinationExists(firstItem, secondItem) {
+			if dryRun {
+				logrus.Info("Dry run, would combine: ", firstItem, " + ", secondItem)
+			} else {
+				combineElements(firstItem, secondItem, db)
+			}
 			createdCombinations++
 		}
 
 		attempts++
 
-		time.Sleep(time.Millisecond * 50)
+		if !dryRun {
+			throttle.wait()
+		}
 	}
 
 	logrus.Info("Finished creating combinations. Total created: ", createdCombinations, ", Total attempts: ", attempts)
+	metrics.logSummary()
 }
@@ -0,0 +1,93 @@
+package main
+
+// Every .go file here is its own program, so the tests are run per program:
+//
+//	go test -race collectData.go depths.go collectData_test.go
+
+import (
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// newTestDB returns an in-memory collector database with the base elements
+// loaded into the caches. One connection keeps every query on the same
+// in-memory database.
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	createTables(db)
+	insertInitialItems(db)
+	initializeLocalCache(db)
+	initializeAttemptedPairs(db)
+	return db
+}
+
+func TestConcurrentCacheAccess(t *testing.T) {
+	db := newTestDB(t)
+
+	const workers, perWorker = 4, 50
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				name := fmt.Sprintf("Item %d-%d", w, i)
+				insertOrUpdateItem(name, "🧪", false, db)
+				insertCombination("Water", name, name, db)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(int64(w)))
+			for i := 0; i < perWorker; i++ {
+				first, second, err := getRandomItems(rng)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				combinationExists(first, second)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The four base elements and everything the workers added
+	if got, want := len(localItemsCache), 4+workers*perWorker; got != want {
+		t.Errorf("cached %d items, want %d", got, want)
+	}
+	for w := 0; w < workers; w++ {
+		name := fmt.Sprintf("Item %d-%d", w, perWorker-1)
+		if !combinationExists("Water", name) {
+			t.Errorf("Water + %s not recorded as attempted", name)
+		}
+	}
+}
+
+func TestRediscoveryKeepsIsNew(t *testing.T) {
+	db := newTestDB(t)
+
+	insertOrUpdateItem("Steam", "💨", true, db)
+	insertOrUpdateItem("Steam", "♨️", false, db)
+
+	var emoji string
+	var isNew bool
+	if err := db.QueryRow("SELECT emoji, isNew FROM items WHERE name = ?", "Steam").Scan(&emoji, &isNew); err != nil {
+		t.Fatal(err)
+	}
+	if !isNew {
+		t.Error("isNew was reset by a later response that wasn't new")
+	}
+	if emoji != "♨️" {
+		t.Errorf("emoji is %q, want the latest %q", emoji, "♨️")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func main() {
+	db, err := sql.Open("sqlite3", "items.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	emoji := make(map[string]string)
+	rows, err := db.Query("SELECT name, emoji FROM items")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for rows.Next() {
+		var name, e string
+		if err = rows.Scan(&name, &e); err != nil {
+			log.Fatal(err)
+		}
+		emoji[name] = e
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err = db.Query("SELECT firstItem, secondItem, resultItem FROM combinations ORDER BY id")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var recipes []recipe
+	for rows.Next() {
+		var r recipe
+		if err = rows.Scan(&r.first, &r.second, &r.result); err != nil {
+			log.Fatal(err)
+		}
+		recipes = append(recipes, r)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	depth, best := computeDepths(recipes)
+
+	deepest := ""
+	for name, d := range depth {
+		if deepest == "" || d > depth[deepest] || (d == depth[deepest] && name < deepest) {
+			deepest = name
+		}
+	}
+	if deepest == "" || depth[deepest] == 0 {
+		fmt.Println("No craftable items found")
+		return
+	}
+
+	var steps []recipe
+	appendSteps(deepest, best, make(map[string]bool), &steps)
+
+	label := func(name string) string {
+		return emoji[name] + " " + name
+	}
+	for i, step := range steps {
+		fmt.Printf("%3d. %s + %s = %s\n", i+1, label(step.first), label(step.second), label(step.result))
+	}
+	fmt.Printf("\nDeepest item: %s at depth %d, %d steps from the base elements\n", label(deepest), depth[deepest], len(steps))
+}
+
+// appendSteps adds the build order of an item to steps, skipping items that
+// are already crafted. Best recipes always go to a lower depth, so this can't
+// loop.
+func appendSteps(name string, best map[string]recipe, crafted map[string]bool, steps *[]recipe) {
+	r, ok := best[name]
+	if crafted[name] || !ok {
+		return
+	}
+	appendSteps(r.first, best, crafted, steps)
+	appendSteps(r.second, best, crafted, steps)
+	crafted[name] = true
+	*steps = append(*steps, r)
+}
@@ -0,0 +1,73 @@
+package main
+
+// The item depths are shared by the tools that need them, build them together
+// with this file, e.g. go run validate.go depths.go emoji.go. The server keeps
+// its own recipeGraph.computeDepthsFrom in main.go, which this follows: it also
+// relaxes from the items a player owns, not only the base elements, over the
+// recipeStep values its API returns.
+
+// baseElements are the items every recipe starts from.
+var baseElements = []string{"Water", "Fire", "Wind", "Earth"}
+
+// recipe is one combination: first and second make result.
+type recipe struct {
+	first, second, result string
+}
+
+// computeDepths returns the depth of every item reachable from the base
+// elements, and for each crafted item the first of its recipes reaching it at
+// that depth. Base elements have depth 0 even when a recipe makes them, any
+// other item is one deeper than the deeper ingredient of its shallowest
+// recipe. Items that can't be crafted get no depth at all.
+func computeDepths(recipes []recipe) (map[string]int, map[string]recipe) {
+	depth := make(map[string]int)
+	isBase := make(map[string]bool)
+	for _, base := range baseElements {
+		depth[base] = 0
+		isBase[base] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, r := range recipes {
+			if isBase[r.result] {
+				continue
+			}
+			d, ok := recipeDepth(depth, r)
+			if !ok {
+				continue
+			}
+			if current, known := depth[r.result]; !known || d < current {
+				depth[r.result] = d
+				changed = true
+			}
+		}
+	}
+
+	// Pick the first shallowest recipe so build orders follow the order of
+	// recipes, like the server's
+	best := make(map[string]recipe)
+	for _, r := range recipes {
+		if _, picked := best[r.result]; picked || isBase[r.result] {
+			continue
+		}
+		if d, ok := recipeDepth(depth, r); ok && d == depth[r.result] {
+			best[r.result] = r
+		}
+	}
+	return depth, best
+}
+
+// recipeDepth is the depth a recipe's result has when crafted with it, ok is
+// false when an ingredient can't be crafted.
+func recipeDepth(depth map[string]int, r recipe) (int, bool) {
+	first, ok := depth[r.first]
+	if !ok {
+		return 0, false
+	}
+	second, ok := depth[r.second]
+	if !ok {
+		return 0, false
+	}
+	return max(first, second) + 1, true
+}
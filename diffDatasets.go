@@ -0,0 +1,176 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type item struct {
+	Emoji string `json:"emoji"`
+	IsNew bool   `json:"isNew"`
+}
+
+type combination struct {
+	First  string `json:"first"`
+	Second string `json:"second"`
+	Result string `json:"result"`
+}
+
+type itemChange struct {
+	Name string `json:"name"`
+	Old  item   `json:"old"`
+	New  item   `json:"new"`
+}
+
+type datasetDiff struct {
+	AddedItems          []string      `json:"addedItems"`
+	RemovedItems        []string      `json:"removedItems"`
+	ChangedItems        []itemChange  `json:"changedItems"`
+	AddedCombinations   []combination `json:"addedCombinations"`
+	RemovedCombinations []combination `json:"removedCombinations"`
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the full diff as JSON instead of a summary")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: diffDatasets [-json] old.db new.db")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	oldItems, oldCombinations := loadDataset(flag.Arg(0))
+	newItems, newCombinations := loadDataset(flag.Arg(1))
+
+	diff := datasetDiff{
+		AddedItems:          make([]string, 0),
+		RemovedItems:        make([]string, 0),
+		ChangedItems:        make([]itemChange, 0),
+		AddedCombinations:   make([]combination, 0),
+		RemovedCombinations: make([]combination, 0),
+	}
+	for name, n := range newItems {
+		o, ok := oldItems[name]
+		if !ok {
+			diff.AddedItems = append(diff.AddedItems, name)
+		} else if o != n {
+			diff.ChangedItems = append(diff.ChangedItems, itemChange{name, o, n})
+		}
+	}
+	for name := range oldItems {
+		if _, ok := newItems[name]; !ok {
+			diff.RemovedItems = append(diff.RemovedItems, name)
+		}
+	}
+	for c := range newCombinations {
+		if !oldCombinations[c] {
+			diff.AddedCombinations = append(diff.AddedCombinations, c)
+		}
+	}
+	for c := range oldCombinations {
+		if !newCombinations[c] {
+			diff.RemovedCombinations = append(diff.RemovedCombinations, c)
+		}
+	}
+
+	sort.Strings(diff.AddedItems)
+	sort.Strings(diff.RemovedItems)
+	sort.Slice(diff.ChangedItems, func(i, j int) bool { return diff.ChangedItems[i].Name < diff.ChangedItems[j].Name })
+	sortCombinations(diff.AddedCombinations)
+	sortCombinations(diff.RemovedCombinations)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(diff); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fmt.Printf("Items: %d -> %d\n", len(oldItems), len(newItems))
+	fmt.Printf("Combinations: %d -> %d\n\n", len(oldCombinations), len(newCombinations))
+	fmt.Printf("%d items added\n", len(diff.AddedItems))
+	for _, name := range diff.AddedItems {
+		fmt.Printf("  + %s %s\n", newItems[name].Emoji, name)
+	}
+	fmt.Printf("%d items removed\n", len(diff.RemovedItems))
+	for _, name := range diff.RemovedItems {
+		fmt.Printf("  - %s %s\n", oldItems[name].Emoji, name)
+	}
+	fmt.Printf("%d items changed\n", len(diff.ChangedItems))
+	for _, change := range diff.ChangedItems {
+		fmt.Printf("  ~ %s: emoji %s -> %s, isNew %t -> %t\n", change.Name, change.Old.Emoji, change.New.Emoji, change.Old.IsNew, change.New.IsNew)
+	}
+	fmt.Printf("%d combinations added, %d removed\n", len(diff.AddedCombinations), len(diff.RemovedCombinations))
+}
+
+// loadDataset reads the items and combinations of a database, opened read-only.
+func loadDataset(path string) (map[string]item, map[combination]bool) {
+	if _, err := os.Stat(path); err != nil {
+		log.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	items := make(map[string]item)
+	rows, err := db.Query("SELECT name, emoji, isNew FROM items")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for rows.Next() {
+		var name string
+		var i item
+		if err = rows.Scan(&name, &i.Emoji, &i.IsNew); err != nil {
+			log.Fatal(err)
+		}
+		items[name] = i
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	combinations := make(map[combination]bool)
+	rows, err = db.Query("SELECT firstItem, secondItem, resultItem FROM combinations")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for rows.Next() {
+		var c combination
+		if err = rows.Scan(&c.First, &c.Second, &c.Result); err != nil {
+			log.Fatal(err)
+		}
+		combinations[c] = true
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return items, combinations
+}
+
+func sortCombinations(combinations []combination) {
+	sort.Slice(combinations, func(i, j int) bool {
+		a, b := combinations[i], combinations[j]
+		if a.First != b.First {
+			return a.First < b.First
+		}
+		return a.Second < b.Second
+	})
+}
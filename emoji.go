@@ -0,0 +1,48 @@
+package main
+
+// The emoji check is shared by the server and the tools that need it, build
+// them together with this file, e.g. go run main.go emoji.go.
+
+import "unicode/utf8"
+
+// isEmoji reports whether s looks like a single emoji grapheme: pictographs
+// optionally joined by ZWJ and followed by variation selectors, skin tones or
+// tags, or a keycap sequence.
+func isEmoji(s string) bool {
+	if s == "" || !utf8.ValidString(s) {
+		return false
+	}
+	pictographs := 0
+	keycap := false
+	for _, r := range s {
+		switch {
+		case r == 0x200D || r == 0xFE0F || (r >= 0xE0020 && r <= 0xE007F):
+			// ZWJ, emoji presentation selector and tag characters
+		case r == 0x20E3:
+			keycap = true
+		case (r >= '0' && r <= '9') || r == '#' || r == '*':
+			// Only valid as the base of a keycap, checked below
+		case isPictograph(r):
+			pictographs++
+		default:
+			return false
+		}
+	}
+	return pictographs > 0 || keycap
+}
+
+func isPictograph(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF,
+		r >= 0x2600 && r <= 0x27BF,
+		r >= 0x2300 && r <= 0x23FF,
+		r >= 0x2B00 && r <= 0x2BFF,
+		r >= 0x2190 && r <= 0x21FF,
+		r >= 0x25A0 && r <= 0x25FF,
+		r >= 0x2900 && r <= 0x297F,
+		r == 0x00A9, r == 0x00AE, r == 0x203C, r == 0x2049, r == 0x2122,
+		r == 0x2139, r == 0x24C2, r == 0x3030, r == 0x303D, r == 0x3297, r == 0x3299:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// asymmetricPairs joins every combination with its reversed ordering when the
+// two disagree on the result. a.firstItem < a.secondItem lists each pair once.
+const asymmetricPairs = `SELECT a.id, a.firstItem, a.secondItem, a.resultItem, b.id, b.resultItem
+FROM combinations a
+JOIN combinations b ON b.firstItem = a.secondItem AND b.secondItem = a.firstItem
+WHERE a.firstItem < a.secondItem AND a.resultItem != b.resultItem
+ORDER BY a.firstItem, a.secondItem`
+
+func main() {
+	collapse := flag.String("collapse", "", "delete one ordering of each asymmetric pair: older keeps the first recorded, newer the last (default only reports)")
+	flag.Parse()
+
+	if *collapse != "" && *collapse != "older" && *collapse != "newer" {
+		log.Fatalf("Unknown -collapse %q, use older or newer", *collapse)
+	}
+
+	db, err := sql.Open("sqlite3", "items.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query(asymmetricPairs)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	found := 0
+	var remove []int64
+	for rows.Next() {
+		var id, reverseID int64
+		var first, second, result, reverseResult string
+		if err = rows.Scan(&id, &first, &second, &result, &reverseID, &reverseResult); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s + %s = %s (#%d), but %s + %s = %s (#%d)\n", first, second, result, id, second, first, reverseResult, reverseID)
+		found++
+
+		// Ids grow with insertion, so the lower one was recorded first
+		older, newer := min(id, reverseID), max(id, reverseID)
+		switch *collapse {
+		case "older":
+			remove = append(remove, newer)
+		case "newer":
+			remove = append(remove, older)
+		}
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Found %d asymmetric pairs\n", found)
+	if len(remove) == 0 {
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, id := range remove {
+		if _, err = tx.Exec("DELETE FROM combinations WHERE id = ?", id); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Removed %d combinations, keeping the %s ordering\n", len(remove), *collapse)
+}
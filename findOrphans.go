@@ -0,0 +1,63 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// orphanCondition matches combinations that reference a name missing from
+// items. sqlite only enforces foreign keys when asked to, so older databases
+// can contain these.
+const orphanCondition = `
+	firstItem NOT IN (SELECT name FROM items)
+	OR secondItem NOT IN (SELECT name FROM items)
+	OR resultItem NOT IN (SELECT name FROM items)`
+
+func main() {
+	deleteOrphans := flag.Bool("delete", false, "delete the orphaned combinations after reporting them")
+	flag.Parse()
+
+	db, err := sql.Open("sqlite3", "items.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT id, firstItem, secondItem, resultItem FROM combinations WHERE" + orphanCondition)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	orphans := 0
+	for rows.Next() {
+		var id int64
+		var first, second, result string
+		if err = rows.Scan(&id, &first, &second, &result); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("#%d: %s + %s = %s\n", id, first, second, result)
+		orphans++
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Found %d orphaned combinations\n", orphans)
+
+	if !*deleteOrphans || orphans == 0 {
+		return
+	}
+
+	res, err := db.Exec("DELETE FROM combinations WHERE" + orphanCondition)
+	if err != nil {
+		log.Fatal(err)
+	}
+	deleted, _ := res.RowsAffected()
+	fmt.Printf("Deleted %d orphaned combinations\n", deleted)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/gob"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// These mirror the types json.go exports with -format gob, gob matches them
+// by field name.
+type gobHeader struct {
+	Items        int
+	Combinations int
+}
+
+type Item struct {
+	Text       string
+	Emoji      string
+	Discovered bool
+}
+
+type Combination struct {
+	First  string
+	Second string
+	Result string
+}
+
+// schema is the same as the one collectData.go creates.
+const schema = `
+CREATE TABLE items (
+	name TEXT PRIMARY KEY,
+	emoji TEXT NOT NULL,
+	isNew BOOLEAN NOT NULL
+);
+CREATE TABLE combinations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	firstItem TEXT NOT NULL,
+	secondItem TEXT NOT NULL,
+	resultItem TEXT NOT NULL,
+	createdAt TIMESTAMP,
+	UNIQUE(firstItem, secondItem),
+	FOREIGN KEY (firstItem) REFERENCES items(name),
+	FOREIGN KEY (secondItem) REFERENCES items(name),
+	FOREIGN KEY (resultItem) REFERENCES items(name)
+);`
+
+func main() {
+	in := flag.String("in", "dataset.gob", "gob export to import")
+	out := flag.String("out", "items.imported.db", "create this new database, existing files are never overwritten")
+	flag.Parse()
+
+	if _, err := os.Stat(*out); err == nil {
+		log.Fatalf("%s already exists", *out)
+	}
+
+	f, err := os.Open(*in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+	dec := gob.NewDecoder(bufio.NewReader(f))
+
+	var header gobHeader
+	if err = dec.Decode(&header); err != nil {
+		log.Fatal("Error reading header: ", err)
+	}
+
+	db, err := sql.Open("sqlite3", *out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err = db.Exec(schema); err != nil {
+		log.Fatal(err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	itemStmt, err := tx.Prepare("INSERT INTO items (name, emoji, isNew) VALUES (?, ?, ?)")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := 0; i < header.Items; i++ {
+		var item Item
+		if err = dec.Decode(&item); err != nil {
+			log.Fatalf("Error reading item %d: %v", i, err)
+		}
+		if _, err = itemStmt.Exec(item.Text, item.Emoji, item.Discovered); err != nil {
+			log.Fatal(err)
+		}
+	}
+	itemStmt.Close()
+
+	combinationStmt, err := tx.Prepare("INSERT INTO combinations (firstItem, secondItem, resultItem) VALUES (?, ?, ?)")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for i := 0; i < header.Combinations; i++ {
+		var c Combination
+		if err = dec.Decode(&c); err != nil {
+			log.Fatalf("Error reading combination %d: %v", i, err)
+		}
+		if _, err = combinationStmt.Exec(c.First, c.Second, c.Result); err != nil {
+			log.Fatal(err)
+		}
+	}
+	combinationStmt.Close()
+
+	if err = tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Imported %d items and %d combinations into %s\n", header.Items, header.Combinations, *out)
+}
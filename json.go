@@ -1,11 +1,18 @@
 package main
 
 import (
+	"archive/zip"
+	"bufio"
 	"database/sql"
+	"encoding/gob"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"path/filepath"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 )
@@ -14,13 +21,57 @@ type Item struct {
 	Text       string `json:"text"`
 	Emoji      string `json:"emoji"`
 	Discovered bool   `json:"discovered"`
+
+	// Only set with -include-metrics, the default export keeps the game's
+	// format
+	*ItemMetrics
+}
+
+// ItemMetrics are the computed fields -include-metrics adds to each item,
+// named like the server's /api/metrics/batch. Depth is nil for items that
+// can't be crafted from the base elements.
+type ItemMetrics struct {
+	Depth   *int `json:"depth"`
+	Recipes int  `json:"recipes"`
+	Uses    int  `json:"uses"`
 }
 
 type ItemsList struct {
 	Elements []Item `json:"elements"`
 }
 
+type Combination struct {
+	First  string `json:"first"`
+	Second string `json:"second"`
+	Result string `json:"result"`
+}
+
+// validateEmoji replaces emoji that aren't a valid emoji grapheme with
+// emojiPlaceholder on export, the database itself is left untouched.
+var (
+	validateEmoji    bool
+	emojiPlaceholder string
+	invalidEmoji     int
+
+	// pretty indents the json format for diffing snapshots.
+	pretty bool
+
+	// metrics holds the metrics of every item with -include-metrics, it is
+	// nil otherwise.
+	metrics map[string]*ItemMetrics
+)
+
 func main() {
+	format := flag.String("format", "json", "export format: json (localStorage import), jsonl (one object per line), cytoscape (Cytoscape.js elements), gob (binary, see importGob.go) or tiers (one file per depth)")
+	out := flag.String("out", "", "items output file (default localStorage.json, items.jsonl, cytoscape.json, dataset.gob or the tiers directory depending on the format)")
+	combinationsOut := flag.String("combinations-out", "", "also write combinations to this file (jsonl only)")
+	flag.BoolVar(&pretty, "pretty", false, "indent the json format instead of minifying it")
+	flag.BoolVar(&validateEmoji, "validate-emoji", false, "replace invalid emoji with the placeholder and report them")
+	archive := flag.String("archive", "", "write items, combinations and a manifest into this zip instead of using -format")
+	flag.StringVar(&emojiPlaceholder, "emoji-placeholder", "❓", "emoji exported in place of invalid ones")
+	includeMetrics := flag.Bool("include-metrics", false, "add depth, recipe and use counts to each item (json, jsonl, tiers and archive)")
+	flag.Parse()
+
 	// Open the SQLite database
 	db, err := sql.Open("sqlite3", "items.db")
 	if err != nil {
@@ -28,8 +79,121 @@ func main() {
 	}
 	defer db.Close()
 
-	// Query the items table
-	rows, err := db.Query("SELECT name, emoji, isNew FROM items")
+	if *includeMetrics {
+		metrics = computeMetrics(db)
+	}
+
+	switch {
+	case *archive != "":
+		exportArchive(db, *archive)
+	case *format == "json":
+		if *out == "" {
+			*out = "localStorage.json"
+		}
+		exportJSON(db, *out)
+	case *format == "jsonl":
+		if *out == "" {
+			*out = "items.jsonl"
+		}
+		exportItemsJSONL(db, *out)
+		if *combinationsOut != "" {
+			exportCombinationsJSONL(db, *combinationsOut)
+		}
+	case *format == "cytoscape":
+		if *out == "" {
+			*out = "cytoscape.json"
+		}
+		exportCytoscape(db, *out)
+	case *format == "gob":
+		if *out == "" {
+			*out = "dataset.gob"
+		}
+		exportGob(db, *out)
+	case *format == "tiers":
+		if *out == "" {
+			*out = "tiers"
+		}
+		exportTiers(db, *out)
+	default:
+		log.Fatalf("Unknown format: %s", *format)
+	}
+
+	if validateEmoji {
+		fmt.Printf("\nReplaced %d invalid emoji with %s\n", invalidEmoji, emojiPlaceholder)
+	}
+}
+
+// checkEmoji applies the -validate-emoji option to an item about to be
+// exported.
+func checkEmoji(item *Item) {
+	if !validateEmoji || isEmoji(item.Emoji) {
+		return
+	}
+	log.Printf("Invalid emoji for %s: %q", item.Text, item.Emoji)
+	item.Emoji = emojiPlaceholder
+	invalidEmoji++
+}
+
+// addMetrics applies the -include-metrics option to an item about to be
+// exported.
+func addMetrics(item *Item) {
+	if metrics == nil {
+		return
+	}
+	if m, ok := metrics[item.Text]; ok {
+		item.ItemMetrics = m
+	} else {
+		item.ItemMetrics = &ItemMetrics{}
+	}
+}
+
+// computeMetrics counts the recipes and uses of every item while reading the
+// combinations once, and computes the depths from them.
+func computeMetrics(db *sql.DB) map[string]*ItemMetrics {
+	rows, err := db.Query("SELECT firstItem, secondItem, resultItem FROM combinations")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rows.Close()
+
+	metrics := make(map[string]*ItemMetrics)
+	get := func(name string) *ItemMetrics {
+		m, ok := metrics[name]
+		if !ok {
+			m = &ItemMetrics{}
+			metrics[name] = m
+		}
+		return m
+	}
+	var recipes []recipe
+	for rows.Next() {
+		var c Combination
+		if err = rows.Scan(&c.First, &c.Second, &c.Result); err != nil {
+			log.Fatal(err)
+		}
+		get(c.Result).Recipes++
+		get(c.First).Uses++
+		if c.Second != c.First {
+			get(c.Second).Uses++
+		}
+		recipes = append(recipes, recipe{c.First, c.Second, c.Result})
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	depth, _ := computeDepths(recipes)
+	for name, d := range depth {
+		d := d
+		get(name).Depth = &d
+	}
+	return metrics
+}
+
+func exportJSON(db *sql.DB, path string) {
+	// Query the items table, ordered so snapshots diff cleanly
+	rows, err := db.Query("SELECT name, emoji, isNew FROM items ORDER BY name")
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -42,6 +206,8 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
+		checkEmoji(&item)
+		addMetrics(&item)
 		itemsList.Elements = append(itemsList.Elements, item)
 	}
 
@@ -49,18 +215,400 @@ func main() {
 		log.Fatal(err)
 	}
 
-	// Marshal data into minified JSON
-	jsonData, err := json.Marshal(itemsList)
+	// Marshal data into JSON, indented with -pretty and minified otherwise.
+	// Fields keep the order of the structs
+	var jsonData []byte
+	if pretty {
+		jsonData, err = json.MarshalIndent(itemsList, "", "  ")
+	} else {
+		jsonData, err = json.Marshal(itemsList)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Save minified JSON to file
-	err = os.WriteFile("localStorage.json", jsonData, 0644)
+	// Save JSON to file
+	err = os.WriteFile(path, jsonData, 0644)
 	if err != nil {
 		log.Fatal("Error writing to file:", err)
 	}
 
 	// Optionally print to stdout as confirmation or for debugging
-	fmt.Printf("Minified JSON data saved to localStorage.json. %d items found", len(itemsList.Elements))
+	fmt.Printf("JSON data saved to %s. %d items found\n", path, len(itemsList.Elements))
+}
+
+// exportItemsJSONL streams the items as newline-delimited JSON, with the same
+// fields as the JSON export.
+func exportItemsJSONL(db *sql.DB, path string) {
+	count := writeFile(path, func(w io.Writer) int { return writeItemsJSONL(db, w) })
+	fmt.Printf("JSON Lines data saved to %s. %d items found\n", path, count)
+}
+
+func exportCombinationsJSONL(db *sql.DB, path string) {
+	count := writeFile(path, func(w io.Writer) int { return writeCombinationsJSONL(db, w) })
+	fmt.Printf("JSON Lines data saved to %s. %d combinations found\n", path, count)
+}
+
+func writeItemsJSONL(db *sql.DB, w io.Writer) int {
+	rows, err := db.Query("SELECT name, emoji, isNew FROM items ORDER BY name")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	count := 0
+	for rows.Next() {
+		var item Item
+		if err = rows.Scan(&item.Text, &item.Emoji, &item.Discovered); err != nil {
+			log.Fatal(err)
+		}
+		checkEmoji(&item)
+		addMetrics(&item)
+		if err = enc.Encode(item); err != nil {
+			log.Fatal("Error writing to file:", err)
+		}
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return count
+}
+
+func writeCombinationsJSONL(db *sql.DB, w io.Writer) int {
+	rows, err := db.Query("SELECT firstItem, secondItem, resultItem FROM combinations ORDER BY id")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	count := 0
+	for rows.Next() {
+		var c Combination
+		if err = rows.Scan(&c.First, &c.Second, &c.Result); err != nil {
+			log.Fatal(err)
+		}
+		if err = enc.Encode(c); err != nil {
+			log.Fatal("Error writing to file:", err)
+		}
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return count
+}
+
+// archiveSchemaVersion is stored in the manifest, bump it when the layout or
+// fields of the archive change.
+const archiveSchemaVersion = 1
+
+type archiveManifest struct {
+	SchemaVersion int       `json:"schemaVersion"`
+	ExportedAt    time.Time `json:"exportedAt"`
+	Items         int       `json:"items"`
+	Combinations  int       `json:"combinations"`
+}
+
+// exportArchive writes items.jsonl, combinations.jsonl and manifest.json into
+// a single zip. Both data files are streamed into the archive.
+func exportArchive(db *sql.DB, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatal("Error creating file:", err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+
+	manifest := archiveManifest{
+		SchemaVersion: archiveSchemaVersion,
+		ExportedAt:    time.Now().UTC(),
+	}
+	manifest.Items = writeArchiveFile(zw, "items.jsonl", func(w io.Writer) int { return writeItemsJSONL(db, w) })
+	manifest.Combinations = writeArchiveFile(zw, "combinations.jsonl", func(w io.Writer) int { return writeCombinationsJSONL(db, w) })
+	writeArchiveFile(zw, "manifest.json", func(w io.Writer) int {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(manifest); err != nil {
+			log.Fatal("Error writing to file:", err)
+		}
+		return 1
+	})
+
+	if err = zw.Close(); err != nil {
+		log.Fatal("Error writing to file:", err)
+	}
+
+	fmt.Printf("Archive saved to %s. %d items and %d combinations found\n", path, manifest.Items, manifest.Combinations)
+}
+
+func writeArchiveFile(zw *zip.Writer, name string, write func(w io.Writer) int) int {
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		log.Fatal("Error writing to file:", err)
+	}
+	return write(w)
+}
+
+type cytoscapeNode struct {
+	Data struct {
+		ID    string `json:"id"`
+		Emoji string `json:"emoji"`
+		IsNew bool   `json:"isNew"`
+	} `json:"data"`
+}
+
+type cytoscapeEdge struct {
+	Data struct {
+		Source string `json:"source"`
+		Target string `json:"target"`
+		Label  string `json:"label"`
+	} `json:"data"`
+}
+
+// exportCytoscape writes the graph in the Cytoscape.js elements format, with
+// an edge from the first to the second ingredient labeled with the result.
+// Nodes and edges are streamed straight from the database.
+func exportCytoscape(db *sql.DB, path string) {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatal("Error creating file:", err)
+	}
+	defer f.Close()
+	w := bufio.NewWriter(f)
+
+	w.WriteString(`{"elements":{"nodes":[`)
+	nodes := writeJSONArray(w, db, "SELECT name, emoji, isNew FROM items ORDER BY name", func(rows *sql.Rows) interface{} {
+		var node cytoscapeNode
+		if err := rows.Scan(&node.Data.ID, &node.Data.Emoji, &node.Data.IsNew); err != nil {
+			log.Fatal(err)
+		}
+		item := Item{Text: node.Data.ID, Emoji: node.Data.Emoji}
+		checkEmoji(&item)
+		node.Data.Emoji = item.Emoji
+		return node
+	})
+	w.WriteString(`],"edges":[`)
+	edges := writeJSONArray(w, db, "SELECT firstItem, secondItem, resultItem FROM combinations ORDER BY id", func(rows *sql.Rows) interface{} {
+		var edge cytoscapeEdge
+		if err := rows.Scan(&edge.Data.Source, &edge.Data.Target, &edge.Data.Label); err != nil {
+			log.Fatal(err)
+		}
+		return edge
+	})
+	w.WriteString(`]}}`)
+
+	if err = w.Flush(); err != nil {
+		log.Fatal("Error writing to file:", err)
+	}
+
+	fmt.Printf("Cytoscape.js data saved to %s. %d nodes and %d edges found\n", path, nodes, edges)
+}
+
+type tiersManifest struct {
+	// Tiers maps each depth to its number of items
+	Tiers       map[int]int `json:"tiers"`
+	Unreachable int         `json:"unreachable"`
+}
+
+// exportTiers writes the items of each depth to tier-<depth>.jsonl in dir,
+// plus a manifest.json with the counts. The depth column is filled by the
+// server's /admin/compute-metrics endpoint.
+func exportTiers(db *sql.DB, dir string) {
+	var hasDepth bool
+	if err := db.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('items') WHERE name = 'depth'").Scan(&hasDepth); err != nil {
+		log.Fatal(err)
+	}
+	if !hasDepth {
+		log.Fatal("items has no depth column, run POST /admin/compute-metrics on the server first")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		log.Fatal("Error creating directory:", err)
+	}
+
+	rows, err := db.Query("SELECT name, emoji, isNew, depth FROM items WHERE depth IS NOT NULL ORDER BY depth, name")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rows.Close()
+
+	manifest := tiersManifest{Tiers: make(map[int]int)}
+	var f *os.File
+	var w *bufio.Writer
+	var enc *json.Encoder
+	closeTier := func() {
+		if f == nil {
+			return
+		}
+		if err := w.Flush(); err != nil {
+			log.Fatal("Error writing to file:", err)
+		}
+		f.Close()
+	}
+	current := -1
+	for rows.Next() {
+		var item Item
+		var depth int
+		if err = rows.Scan(&item.Text, &item.Emoji, &item.Discovered, &depth); err != nil {
+			log.Fatal(err)
+		}
+		// Rows come ordered by depth, so each tier file is written in one go
+		if depth != current {
+			closeTier()
+			f, err = os.Create(filepath.Join(dir, fmt.Sprintf("tier-%d.jsonl", depth)))
+			if err != nil {
+				log.Fatal("Error creating file:", err)
+			}
+			w = bufio.NewWriter(f)
+			enc = json.NewEncoder(w)
+			current = depth
+		}
+		checkEmoji(&item)
+		addMetrics(&item)
+		if err = enc.Encode(item); err != nil {
+			log.Fatal("Error writing to file:", err)
+		}
+		manifest.Tiers[depth]++
+	}
+	closeTier()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err = db.QueryRow("SELECT COUNT(*) FROM items WHERE depth IS NULL").Scan(&manifest.Unreachable); err != nil {
+		log.Fatal(err)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "manifest.json"), data, 0644); err != nil {
+		log.Fatal("Error writing to file:", err)
+	}
+
+	fmt.Printf("Tiers saved to %s. %d tiers, %d unreachable items left out\n", dir, len(manifest.Tiers), manifest.Unreachable)
+}
+
+// gobHeader starts a gob export, it is followed by Items items and then
+// Combinations combinations, each encoded as its own value.
+type gobHeader struct {
+	Items        int
+	Combinations int
+}
+
+// exportGob writes the dataset in the compact gob format read by
+// importGob.go. Rows are encoded one at a time, so the export is streamed.
+func exportGob(db *sql.DB, path string) {
+	var header gobHeader
+	if err := db.QueryRow("SELECT (SELECT COUNT(*) FROM items), (SELECT COUNT(*) FROM combinations)").Scan(&header.Items, &header.Combinations); err != nil {
+		log.Fatal(err)
+	}
+
+	writeFile(path, func(w io.Writer) int {
+		enc := gob.NewEncoder(w)
+		if err := enc.Encode(header); err != nil {
+			log.Fatal("Error writing to file:", err)
+		}
+
+		items := writeGobRows(enc, db, "SELECT name, emoji, isNew FROM items ORDER BY name", func(rows *sql.Rows) interface{} {
+			var item Item
+			if err := rows.Scan(&item.Text, &item.Emoji, &item.Discovered); err != nil {
+				log.Fatal(err)
+			}
+			checkEmoji(&item)
+			return item
+		})
+		combinations := writeGobRows(enc, db, "SELECT firstItem, secondItem, resultItem FROM combinations ORDER BY id", func(rows *sql.Rows) interface{} {
+			var c Combination
+			if err := rows.Scan(&c.First, &c.Second, &c.Result); err != nil {
+				log.Fatal(err)
+			}
+			return c
+		})
+		// Rows written while exporting would make the header lie
+		if items != header.Items || combinations != header.Combinations {
+			log.Fatal("Database changed during the export")
+		}
+		return items
+	})
+
+	fmt.Printf("Gob data saved to %s. %d items and %d combinations found\n", path, header.Items, header.Combinations)
+}
+
+// writeGobRows encodes the value scan returns for each row of query and
+// returns the row count.
+func writeGobRows(enc *gob.Encoder, db *sql.DB, query string, scan func(rows *sql.Rows) interface{}) int {
+	rows, err := db.Query(query)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		if err := enc.Encode(scan(rows)); err != nil {
+			log.Fatal("Error writing to file:", err)
+		}
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return count
+}
+
+// writeJSONArray writes the comma separated elements of a JSON array, one
+// for each row of query as returned by scan. It returns the element count.
+func writeJSONArray(w *bufio.Writer, db *sql.DB, query string, scan func(rows *sql.Rows) interface{}) int {
+	rows, err := db.Query(query)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		data, err := json.Marshal(scan(rows))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if count > 0 {
+			w.WriteByte(',')
+		}
+		w.Write(data)
+		count++
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return count
+}
+
+// writeFile creates path and lets write fill it through a buffer, returning
+// what write returns.
+func writeFile(path string, write func(w io.Writer) int) int {
+	f, err := os.Create(path)
+	if err != nil {
+		log.Fatal("Error creating file:", err)
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	count := write(w)
+
+	if err = w.Flush(); err != nil {
+		log.Fatal("Error writing to file:", err)
+	}
+	return count
 }
@@ -0,0 +1,129 @@
+package main
+
+// Every .go file here is its own program, so the tests are run per program:
+//
+//	go test -bench Load json.go depths.go emoji.go json_test.go
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// benchmarkItems and benchmarkCombinations size the dataset both load
+// benchmarks decode.
+const (
+	benchmarkItems        = 10000
+	benchmarkCombinations = 50000
+)
+
+var (
+	exportsOnce sync.Once
+	gobExport   []byte
+	jsonlExport []byte
+)
+
+// loadExports exports the same generated dataset as gob and as jsonl items
+// followed by combinations, which is what importing either needs to read.
+func loadExports(b *testing.B) {
+	exportsOnce.Do(func() {
+		db, err := sql.Open("sqlite3", ":memory:")
+		if err != nil {
+			b.Fatal(err)
+		}
+		defer db.Close()
+		db.SetMaxOpenConns(1)
+
+		_, err = db.Exec(`CREATE TABLE items (name TEXT PRIMARY KEY, emoji TEXT NOT NULL, isNew BOOLEAN NOT NULL);
+CREATE TABLE combinations (id INTEGER PRIMARY KEY AUTOINCREMENT, firstItem TEXT NOT NULL, secondItem TEXT NOT NULL, resultItem TEXT NOT NULL)`)
+		if err != nil {
+			b.Fatal(err)
+		}
+		tx, err := db.Begin()
+		if err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < benchmarkItems; i++ {
+			if _, err = tx.Exec("INSERT INTO items VALUES (?, ?, ?)", fmt.Sprintf("Item %d", i), "🧪", i%7 == 0); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for i := 0; i < benchmarkCombinations; i++ {
+			first, second, result := fmt.Sprintf("Item %d", i%benchmarkItems), fmt.Sprintf("Item %d", i*31%benchmarkItems), fmt.Sprintf("Item %d", i*17%benchmarkItems)
+			if _, err = tx.Exec("INSERT INTO combinations (firstItem, secondItem, resultItem) VALUES (?, ?, ?)", first, second, result); err != nil {
+				b.Fatal(err)
+			}
+		}
+		if err = tx.Commit(); err != nil {
+			b.Fatal(err)
+		}
+
+		path := filepath.Join(b.TempDir(), "dataset.gob")
+		exportGob(db, path)
+		if gobExport, err = os.ReadFile(path); err != nil {
+			b.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		writeItemsJSONL(db, &buf)
+		writeCombinationsJSONL(db, &buf)
+		jsonlExport = buf.Bytes()
+	})
+}
+
+func BenchmarkLoadGob(b *testing.B) {
+	loadExports(b)
+	b.SetBytes(int64(len(gobExport)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Decoded the same way as importGob.go
+		dec := gob.NewDecoder(bufio.NewReader(bytes.NewReader(gobExport)))
+		var header gobHeader
+		if err := dec.Decode(&header); err != nil {
+			b.Fatal(err)
+		}
+		for j := 0; j < header.Items; j++ {
+			var item Item
+			if err := dec.Decode(&item); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for j := 0; j < header.Combinations; j++ {
+			var c Combination
+			if err := dec.Decode(&c); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
+
+func BenchmarkLoadJSONL(b *testing.B) {
+	loadExports(b)
+	b.SetBytes(int64(len(jsonlExport)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		dec := json.NewDecoder(bufio.NewReader(bytes.NewReader(jsonlExport)))
+		for j := 0; j < benchmarkItems; j++ {
+			var item Item
+			if err := dec.Decode(&item); err != nil {
+				b.Fatal(err)
+			}
+		}
+		for {
+			var c Combination
+			if err := dec.Decode(&c); err == io.EOF {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+}
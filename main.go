@@ -1,242 +1,4763 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"container/list"
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"hash/fnv"
 	"html/template"
-	"log"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
 )
 
 var (
-	templates *template.Template
-	db        *sql.DB
+	templates   *template.Template
+	templatesMu sync.RWMutex
+	db          *sql.DB
+
+	// devMode re-parses the templates on every request so they can be
+	// edited without restarting the server.
+	devMode bool
+	// adminToken guards the /admin endpoints, they are disabled when empty.
+	adminToken string
+	// maxQueryLength is the longest search query accepted, in bytes.
+	maxQueryLength int
+	// minQueryLength is the shortest search query that is run, in
+	// characters after trimming whitespace.
+	minQueryLength int
+	// searchLimit caps the number of search results, searches hitting it are
+	// reported as limited.
+	searchLimit int
+	// collectorPath is the collector binary run by /admin/discover, the
+	// endpoint is disabled when empty.
+	collectorPath string
+	// discoverLimit is the number of new combinations a discover job stops at.
+	discoverLimit int
+	// readOnly opens the database read-only and disables every endpoint
+	// that writes to it.
+	readOnly bool
+	// cacheMaxAge is sent as the max-age of item pages, in seconds. No
+	// Cache-Control header is sent when it is 0.
+	cacheMaxAge int
+	// dbMaxOpen, dbMaxIdle and dbConnMaxLifetime tune the connection pool
+	// in initDB. sqlite allows one writer at a time, so more open connections
+	// only help concurrent reads and make writers wait on the lock more.
+	// With a limit, no request may query while it still holds open rows,
+	// or it waits on a connection forever once the pool is used up.
+	dbMaxOpen         int
+	dbMaxIdle         int
+	dbConnMaxLifetime time.Duration
+	// emojiPlaceholder is shown by emojiOr in place of a missing or broken
+	// emoji.
+	emojiPlaceholder string
 )
 
 func main() {
-	initDB("items.db")
+	flag.BoolVar(&devMode, "dev", false, "re-parse templates on every request")
+	flag.StringVar(&adminToken, "admin-token", "", "bearer token for the /admin endpoints (disabled when empty)")
+	flag.IntVar(&maxQueryLength, "max-query-length", 100, "longest accepted search query in bytes")
+	flag.IntVar(&minQueryLength, "min-query-length", 1, "shortest search query in characters that is run, shorter ones only prompt for more")
+	flag.IntVar(&searchLimit, "search-limit", 1000, "maximum number of search results")
+	flag.BoolVar(&readOnly, "read-only", false, "never write to the database, endpoints that would are disabled")
+	flag.IntVar(&cacheMaxAge, "cache-max-age", 0, "max-age in seconds for the Cache-Control header of item pages (0 sends none)")
+	pageCacheSize := flag.Int("page-cache-size", 1000, "number of rendered item pages kept in memory (0 disables the cache)")
+	tlsCert := flag.String("tls-cert", "", "serve HTTPS with this certificate file, plain HTTP then redirects to it")
+	tlsKey := flag.String("tls-key", "", "private key file for -tls-cert")
+	tlsAddr := flag.String("tls-addr", ":8443", "address to serve HTTPS on when -tls-cert is set")
+	flag.StringVar(&collectorPath, "collector", "", "collector binary run by /admin/discover (disabled when empty)")
+	flag.IntVar(&discoverLimit, "discover-limit", 200, "new combinations after which a discover job stops")
+	flag.IntVar(&dbMaxOpen, "db-max-open", 4, "most open database connections, more allow more concurrent reads but contend for the write lock (0 for no limit)")
+	flag.IntVar(&dbMaxIdle, "db-max-idle", 4, "most idle database connections kept open, keep it at db-max-open to avoid reopening the file")
+	flag.DurationVar(&dbConnMaxLifetime, "db-conn-max-lifetime", 0, "close database connections after this long, e.g. 1h (0 keeps them)")
+	flag.StringVar(&emojiPlaceholder, "emoji-placeholder", "❓", "shown in place of missing or invalid emoji")
+	logLevel := flag.String("log-level", "info", "log level: debug, info, warn or error")
+	logJSON := flag.Bool("log-json", false, "log as JSON instead of text")
+	flag.Parse()
+
+	level, err := logrus.ParseLevel(*logLevel)
+	if err != nil {
+		logrus.Fatal("Invalid log level: ", err)
+	}
+	logrus.SetLevel(level)
+	if *logJSON {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+
+	if searchLimit <= 0 {
+		logrus.Fatal("search-limit must be positive")
+	}
+	if minQueryLength < 1 {
+		logrus.Fatal("min-query-length must be at least 1")
+	}
+	if dbMaxOpen < 0 || dbMaxIdle < 0 || dbConnMaxLifetime < 0 {
+		logrus.Fatal("db-max-open, db-max-idle and db-conn-max-lifetime can't be negative")
+	}
+	if (*tlsCert == "") != (*tlsKey == "") {
+		logrus.Fatal("tls-cert and tls-key must be set together")
+	}
+	if devMode {
+		// Cached pages would hide template edits
+		*pageCacheSize = 0
+	}
+	itemPages = newPageCache(*pageCacheSize)
+
+	if readOnly {
+		logrus.Info("Read-only mode, write endpoints are disabled")
+		initDB("file:items.db?mode=ro")
+	} else {
+		initDB("items.db")
+	}
 	defer db.Close()
-	templates = template.Must(template.New("").ParseGlob("templates/*.html"))
+	if err := reloadTemplates(); err != nil {
+		// The error names the template file and line
+		if !devMode {
+			logrus.Fatalf("Failed to parse templates: %v", err)
+		}
+		logrus.Errorf("Failed to parse templates, serving the error until they are fixed: %v", err)
+	}
 
 	mux := http.NewServeMux()
+	site := stripTrailingSlash(mux)
 
 	logMux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		log.Printf("%s %s\n", r.Method, r.URL.Path)
-		mux.ServeHTTP(w, r)
+		start := time.Now()
+		if devMode {
+			serveDevMode(site, w, r)
+		} else {
+			site.ServeHTTP(w, r)
+		}
+		logrus.WithFields(logrus.Fields{
+			"method":   r.Method,
+			"path":     r.URL.Path,
+			"remote":   r.RemoteAddr,
+			"duration": time.Since(start),
+		}).Info("Handled request")
 	})
 
 	mux.HandleFunc("/", serveStartPage)
 	mux.HandleFunc("/search", handleSearch)
 	mux.HandleFunc("/count", handleItemCount)
+	mux.HandleFunc("GET /localStorage.json", handleLocalStorage)
 	mux.HandleFunc("/i/{name}", handleItem)
+	mux.HandleFunc("GET /fragment/i/{name}", handleItemFragment)
+	mux.HandleFunc("GET /card/{name}", handleCard)
+	mux.HandleFunc("GET /i/{name}/tree.svg", handleTreeSVG)
+	mux.HandleFunc("GET /e/{emoji}", handleEmoji)
+	// Unversioned /api paths stay aliases of v1 for existing clients
+	registerAPI(mux, "/api/v1", apiV1Routes())
+	registerAPI(mux, "/api", apiV1Routes())
+	mux.HandleFunc("GET /recipe/{name}", handleRecipe)
+	mux.HandleFunc("GET /recipes/{name}", handleRecipes)
+	mux.HandleFunc("GET /easiest", handleEasiest)
+	mux.HandleFunc("GET /browse", handleBrowse)
+	mux.HandleFunc("GET /hubs", handleHubs)
+	mux.HandleFunc("GET /most-recipes", handleMostRecipes)
+	mux.HandleFunc("GET /unique-recipe", handleUniqueRecipe)
+	mux.HandleFunc("GET /recently-updated", handleRecentlyUpdated)
+	mux.HandleFunc("GET /tier1", handleTier1)
+	mux.HandleFunc("GET /tier/{n}", handleTier)
+	mux.HandleFunc("GET /emoji-stats", handleEmojiStats)
+	mux.HandleFunc("GET /stats", handleStats)
+	mux.HandleFunc("POST /admin/reload-templates", requireAdmin(handleReloadTemplates))
+	mux.HandleFunc("POST /admin/backup", requireAdmin(handleBackup))
+	mux.HandleFunc("POST /admin/compute-metrics", requireAdmin(requireWritable(handleComputeMetrics)))
+	mux.HandleFunc("POST /admin/discover", requireAdmin(requireWritable(handleDiscover)))
+	mux.HandleFunc("GET /admin/discover/status", requireAdmin(handleDiscoverStatus))
 
-	log.Println("Server started on :8080")
-	http.ListenAndServe(":8080", logMux)
-}
+	if *tlsCert == "" && *tlsKey == "" {
+		logrus.Info("Server started on :8080")
+		http.ListenAndServe(":8080", logMux)
+		return
+	}
 
-func serveStartPage(w http.ResponseWriter, r *http.Request) {
-	log.Println("Serving start page")
-	totalItems, _ := getTotalItemCount()
-	_ = totalItems
-	data := struct {
-		Title      string
-		TotalItems int
-		MaybeItem  string
-	}{Title: "Infinite Craft Search", TotalItems: totalItems, MaybeItem: ""}
-	if err := templates.ExecuteTemplate(w, "start.html", data); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Plain HTTP only redirects once HTTPS is served, ListenAndServeTLS
+	// enables HTTP/2 on its own
+	go func() {
+		logrus.Info("Redirecting HTTP on :8080 to HTTPS")
+		if err := http.ListenAndServe(":8080", redirectToHTTPS(*tlsAddr)); err != nil {
+			logrus.Errorf("HTTP redirect server stopped: %v", err)
+		}
+	}()
+	logrus.Infof("Server started on %s with TLS", *tlsAddr)
+	if err := http.ListenAndServeTLS(*tlsAddr, *tlsCert, *tlsKey, logMux); err != nil {
+		logrus.Fatal(err)
 	}
 }
 
-func handleSearch(w http.ResponseWriter, r *http.Request) {
-	searchQuery := r.FormValue("item")
-	log.Printf("Handling search for query: '%s'", searchQuery)
+// redirectToHTTPS sends every request to the same URL on the HTTPS address.
+func redirectToHTTPS(tlsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(tlsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		target := url.URL{Scheme: "https", Host: host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+		http.Redirect(w, r, target.String(), http.StatusMovedPermanently)
+	})
+}
+
+// stripTrailingSlash redirects paths ending in a slash to the same path
+// without it, none of the routes use one. The escaped path is checked, so an
+// item name ending in an encoded slash is left alone. Leading slashes are
+// collapsed as well, the mux hasn't cleaned the path yet and browsers follow
+// a Location of //host to another site.
+func stripTrailingSlash(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		escaped := r.URL.EscapedPath()
+		if escaped == "/" || !strings.HasSuffix(escaped, "/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		target := "/" + strings.Trim(escaped, "/")
+		if r.URL.RawQuery != "" {
+			target += "?" + r.URL.RawQuery
+		}
+		// 308 keeps the method and body of API requests
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, target, code)
+	})
+}
+
+// templateFuncs are the helpers available in every template.
+var templateFuncs = template.FuncMap{
+	// emojiOr shows emojiPlaceholder in place of a missing or broken emoji,
+	// until repairEmoji.go fixed the data
+	"emojiOr": func(emoji string) string {
+		if isEmoji(emoji) {
+			return emoji
+		}
+		return emojiPlaceholder
+	},
+}
+
+// templatesPattern matches the template files the server renders.
+const templatesPattern = "templates/*.html"
+
+func parseTemplates(pattern string) (*template.Template, error) {
+	return template.New("").Funcs(templateFuncs).ParseGlob(pattern)
+}
+
+// executeTemplate renders a template from the current set.
+func executeTemplate(w io.Writer, name string, data interface{}) error {
+	templatesMu.RLock()
+	t := templates
+	templatesMu.RUnlock()
+	return t.ExecuteTemplate(w, name, data)
+}
 
-	items, limited, err := searchItems(searchQuery)
+// reloadTemplates parses the templates and swaps them in. The current set is
+// kept if parsing fails.
+func reloadTemplates() error {
+	t, err := parseTemplates(templatesPattern)
 	if err != nil {
-		log.Printf("Error fetching items: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return err
+	}
+	templatesMu.Lock()
+	templates = t
+	templatesMu.Unlock()
+	return nil
+}
+
+// serveDevMode re-parses the templates before each request and shows parse
+// errors instead of the page, so a broken template can be fixed without
+// restarting the server.
+func serveDevMode(next http.Handler, w http.ResponseWriter, r *http.Request) {
+	if err := reloadTemplates(); err != nil {
+		logrus.Errorf("Failed to parse templates: %v", err)
+		http.Error(w, "Failed to parse templates:\n\n"+err.Error(), http.StatusInternalServerError)
 		return
 	}
+	next.ServeHTTP(w, r)
+}
+
+// requireAdmin only lets requests carrying the admin bearer token through.
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if adminToken == "" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+adminToken {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
 
+// apiRoute is an endpoint of the JSON API, its path is relative to the
+// version prefix.
+type apiRoute struct {
+	method  string
+	path    string
+	handler http.HandlerFunc
+}
 
-		Items   []Item
-		Limited bool
-	}{Items: items, Limited: limited})
-	if err != nil {
-		log.Printf("Error executing template: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+// apiV1Routes are the endpoints of version 1 of the JSON API. Their response
+// shapes stay as they are, changes go into a new version with its own table.
+func apiV1Routes() []apiRoute {
+	return []apiRoute{
+		{"GET", "/items/{name}", handleItemAPI},
+		{"GET", "/search", handleSearchAPI},
+		{"", "/combinations", handleCombinationsAPI},
+		{"GET", "/combinations/by-ingredient/{name}", handleCombinationsByIngredient},
+		{"GET", "/combinations/search", handleCombinationsSearch},
+		{"POST", "/craftable", handleCraftable},
+		{"POST", "/plan", handlePlan},
+		{"POST", "/next-goals", handleNextGoals},
+		{"POST", "/chain", handleChain},
+		{"GET", "/attempted", handleAttempted},
+		{"POST", "/decode-recipe", handleDecodeRecipe},
+		{"POST", "/metrics/batch", handleMetricsBatch},
+		{"GET", "/suggest-mix", handleSuggestMix},
+		{"POST", "/contribute", requireAdmin(requireWritable(handleContribute))},
+		{"GET", "/adjacency/{name}", handleAdjacency},
+		{"GET", "/random-combination", handleRandomCombination},
+		{"GET", "/complexity/{name}", handleComplexity},
+		{"GET", "/tree-contains/{name}", handleTreeContains},
+		{"GET", "/history/{name}", handleHistory},
+		{"GET", "/base-producing", handleBaseProducing},
+		{"GET", "/convergent", handleConvergent},
+		{"GET", "/self-combinations", handleSelfCombinations},
+		{"GET", "/recipe-count-distribution", handleRecipeCountDistribution},
+		{"GET", "/self-dependent", handleSelfDependent},
+		{"GET", "/why-unreachable/{name}", handleWhyUnreachable},
 	}
 }
 
-func handleItemCount(w http.ResponseWriter, r *http.Request) {
-	count, err := getTotalItemCount()
+// registerAPI serves routes under prefix.
+func registerAPI(mux *http.ServeMux, prefix string, routes []apiRoute) {
+	for _, route := range routes {
+		pattern := prefix + route.path
+		if route.method != "" {
+			pattern = route.method + " " + pattern
+		}
+		mux.HandleFunc(pattern, route.handler)
+	}
+}
+
+// requireWritable hides endpoints that write to the database in read-only
+// mode.
+func requireWritable(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if readOnly {
+			http.NotFound(w, r)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func handleReloadTemplates(w http.ResponseWriter, r *http.Request) {
+	if err := reloadTemplates(); err != nil {
+		logrus.Errorf("Error reloading templates: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	itemPages.clear()
+	logrus.Info("Templates reloaded")
+	fmt.Fprintln(w, "Templates reloaded")
+}
+
+// handleBackup writes a consistent copy of the live database to the given
+// path using VACUUM INTO, which refuses to overwrite existing files.
+func handleBackup(w http.ResponseWriter, r *http.Request) {
+	path := r.FormValue("path")
+	if path == "" {
+		http.Error(w, "Missing backup path", http.StatusBadRequest)
+		return
+	}
+
+	start := time.Now()
+	if _, err := db.Exec(`VACUUM INTO ?`, path); err != nil {
+		logrus.Errorf("Error backing up database to %s: %v", path, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	duration := time.Since(start)
+
+	info, err := os.Stat(path)
 	if err != nil {
-		http.Error(w, "Failed to get item count", http.StatusInternalServerError)
+		logrus.Errorf("Error reading backup %s: %v", path, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	fmt.Fprintf(w, "%d", count)
+	logrus.Infof("Backed up database to %s (%d bytes) in %s", path, info.Size(), duration)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Path       string `json:"path"`
+		Size       int64  `json:"size"`
+		DurationMs int64  `json:"durationMs"`
+	}{Path: path, Size: info.Size(), DurationMs: duration.Milliseconds()})
 }
 
-func handleItem(w http.ResponseWriter, r *http.Request) {
-	name := r.PathValue("name")
+// discoverDuration bounds how long a single discover job may run.
+const discoverDuration = 10 * time.Minute
+
+// discoverOutputLines is how many lines of collector output the status keeps.
+const discoverOutputLines = 20
+
+// discoverJob is the state of the last targeted crawl started through
+// /admin/discover. Only one job runs at a time.
+type discoverJob struct {
+	mu                sync.Mutex
+	target            string
+	running           bool
+	startedAt         time.Time
+	finishedAt        time.Time
+	startCombinations int
+	err               error
+	output            []string
+}
+
+var discover discoverJob
+
+// Write keeps the last discoverOutputLines lines written by the collector.
+func (j *discoverJob) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		j.output = append(j.output, line)
+	}
+	if len(j.output) > discoverOutputLines {
+		j.output = j.output[len(j.output)-discoverOutputLines:]
+	}
+	return len(p), nil
+}
 
-	item, err := getItem(name)
+// handleDiscover starts the collector in its targeted mode for the given item
+// in the background, poll /admin/discover/status for its progress.
+func handleDiscover(w http.ResponseWriter, r *http.Request) {
+	if collectorPath == "" {
+		http.Error(w, "Discovery is disabled, start the server with -collector", http.StatusServiceUnavailable)
+		return
+	}
+	target := r.FormValue("target")
+	if target == "" {
+		http.Error(w, "Missing target", http.StatusBadRequest)
+		return
+	}
+	item, err := getItem(r.Context(), target)
 	if err != nil {
-		log.Printf("Error fetching item: %v", err)
+		logrus.Errorf("Error fetching item: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
-
 	if item == nil {
-		log.Printf("Item not found: %s", name)
 		http.Error(w, "Not Found", http.StatusNotFound)
 		return
 	}
-
-	combinations, err := getCombinations(item)
+	combinations, err := getTotalCombinationCount()
 	if err != nil {
-		log.Printf("Error fetching combinations: %v", err)
+		logrus.Errorf("Error fetching combination count: %v", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	tempWriter := &bytes.Buffer{}
-	err = templates.ExecuteTemplate(tempWriter, "item.html", struct {
-		Item         *Item
-		Combinations []Combination
-	}{Item: item, Combinations: combinations})
-	if err != nil {
-		log.Printf("Error executing template: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	discover.mu.Lock()
+	if discover.running {
+		discover.mu.Unlock()
+		http.Error(w, "A discover job is already running for "+discover.target, http.StatusConflict)
 		return
 	}
-	itemHTML := template.HTML(tempWriter.String())
+	discover.target = item.Name
+	discover.running = true
+	discover.startedAt = time.Now()
+	discover.finishedAt = time.Time{}
+	discover.startCombinations = combinations
+	discover.err = nil
+	discover.output = nil
+	discover.mu.Unlock()
 
-	totalItems, _ := getTotalItemCount()
+	go runDiscover(item.Name)
 
-	err = templates.ExecuteTemplate(w, "start.html", struct {
-		Title      string
-		TotalItems int
-		MaybeItem  template.HTML
-	}{Title: fmt.Sprintf("%s | Infinite Craft Search", item.Name), TotalItems: totalItems, MaybeItem: itemHTML})
+	logrus.Infof("Started discover job for %s", item.Name)
+	w.WriteHeader(http.StatusAccepted)
+	fmt.Fprintf(w, "Started discover job for %s\n", item.Name)
 }
 
-func getItem(name string) (*Item, error) {
-	var item Item
-	stmt, err := db.Prepare(`SELECT name, emoji, isNew FROM items WHERE name = ?`)
+func runDiscover(target string) {
+	ctx, cancel := context.WithTimeout(context.Background(), discoverDuration+time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, collectorPath,
+		"-target", target,
+		"-max-combinations", strconv.Itoa(discoverLimit),
+		"-duration", discoverDuration.String())
+	cmd.Stdout = &discover
+	cmd.Stderr = &discover
+	err := cmd.Run()
 	if err != nil {
-		return nil, err
+		logrus.Errorf("Discover job for %s failed: %v", target, err)
+	} else {
+		logrus.Infof("Discover job for %s finished", target)
 	}
-	defer stmt.Close()
 
-	row := stmt.QueryRow(name)
-	if err := row.Scan(&item.Name, &item.Emoji, &item.IsNew); err != nil {
-		return nil, err
+	discover.mu.Lock()
+	defer discover.mu.Unlock()
+	discover.running = false
+	discover.finishedAt = time.Now()
+	discover.err = err
+}
+
+func handleDiscoverStatus(w http.ResponseWriter, r *http.Request) {
+	// The collector writes to the same database, so new combinations show
+	// up in the count while the job runs
+	combinations, err := getTotalCombinationCount()
+	if err != nil {
+		logrus.Errorf("Error fetching combination count: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
 
-	return &item, nil
+	discover.mu.Lock()
+	defer discover.mu.Unlock()
+	status := struct {
+		Target          string     `json:"target,omitempty"`
+		Running         bool       `json:"running"`
+		StartedAt       *time.Time `json:"startedAt,omitempty"`
+		FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+		NewCombinations int        `json:"newCombinations"`
+		Error           string     `json:"error,omitempty"`
+		Output          []string   `json:"output"`
+	}{
+		Target:  discover.target,
+		Running: discover.running,
+		Output:  append([]string{}, discover.output...),
+	}
+	if !discover.startedAt.IsZero() {
+		status.StartedAt = &discover.startedAt
+		status.NewCombinations = combinations - discover.startCombinations
+	}
+	if !discover.finishedAt.IsZero() {
+		status.FinishedAt = &discover.finishedAt
+	}
+	if discover.err != nil {
+		status.Error = discover.err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
 }
 
-func getCombinations(item *Item) ([]Combination, error) {
-	stmt, err := db.Prepare(`SELECT
-	A.name AS firstName,
-	A.emoji AS firstEmoji,
-	B.name AS secondName,
-	B.emoji AS secondEmoji
-FROM
-	combinations
-JOIN
-	items A ON combinations.firstItem = A.name
-JOIN
-	items B ON combinations.secondItem = B.name
-WHERE
-	combinations.resultItem = ?`)
+// handleComputeMetrics stores the depth and build cost of every item in the
+// items table, adding the columns on first use. Unreachable items get NULL.
+func handleComputeMetrics(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	graph, err := loadRecipeGraph()
 	if err != nil {
-		return nil, err
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
-	defer stmt.Close()
 
-	rows, err := stmt.Query(item.Name)
+	updated, err := storeItemMetrics(graph)
 	if err != nil {
-		return nil, err
+		logrus.Errorf("Error storing item metrics: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
-	defer rows.Close()
+	logrus.Infof("Stored metrics for %d items in %s", updated, time.Since(start))
+	fmt.Fprintf(w, "Stored metrics for %d items\n", updated)
+}
 
-	combinations := make([]Combination, 0)
+func serveStartPage(w http.ResponseWriter, r *http.Request) {
+	logrus.Debug("Serving start page")
 
-	for rows.Next() {
-		combination := Combination{
-			Item1:  &Item{},
-			Item2:  &Item{},
-			Result: item,
+	// The "did you know" widget is a nice to have, the page works without it
+	content := &bytes.Buffer{}
+	if combination, err := getRandomCombination(r.Context()); err != nil {
+		logrus.Errorf("Error fetching random combination: %v", err)
+	} else if combination != nil {
+		if err := executeTemplate(content, "didYouKnow.html", combination); err != nil {
+			logrus.Errorf("Error executing template: %v", err)
 		}
-		if err := rows.Scan(&combination.Item1.Name, &combination.Item1.Emoji, &combination.Item2.Name, &combination.Item2.Emoji); err != nil {
-			return nil, err
-		}
-		log.Printf("Combination: %v", combination)
-		combinations = append(combinations, combination)
 	}
 
-	return combinations, nil
+	if err := renderStartPage(w, "Infinite Craft Search", template.HTML(content.String())); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
 }
 
-func initDB(dataSourceName string) {
-	var err error
-	db, err = sql.Open("sqlite3", dataSourceName)
+func handleRandomCombination(w http.ResponseWriter, r *http.Request) {
+	combination, err := getRandomCombination(r.Context())
 	if err != nil {
-		log.Fatal(err)
+		logrus.Errorf("Error fetching random combination: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
-	if err = db.Ping(); err != nil {
-		log.Fatal(err)
+	if combination == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	type jsonItem struct {
+		Name  string `json:"name"`
+		Emoji string `json:"emoji"`
 	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		First       jsonItem `json:"first"`
+		Second      jsonItem `json:"second"`
+		Result      jsonItem `json:"result"`
+		ResultIsNew bool     `json:"resultIsNew"`
+	}{
+		First:       jsonItem{combination.Item1.Name, combination.Item1.Emoji},
+		Second:      jsonItem{combination.Item2.Name, combination.Item2.Emoji},
+		Result:      jsonItem{combination.Result.Name, combination.Result.Emoji},
+		ResultIsNew: combination.Result.IsNew,
+	})
 }
 
-type Item struct {
-	Name  string
-	Emoji string
-	IsNew bool
+// renderStartPage renders the start page layout with the dataset counters,
+// with content placed below the search bar.
+func renderStartPage(w http.ResponseWriter, title string, content template.HTML) error {
+	totalItems, _ := getTotalItemCount()
+	totalCombinations, _ := getTotalCombinationCount()
+	return executeTemplate(w, "start.html", struct {
+		Title             string
+		TotalItems        int
+		TotalCombinations int
+		MaybeItem         template.HTML
+	}{Title: title, TotalItems: totalItems, TotalCombinations: totalCombinations, MaybeItem: content})
 }
 
-type Combination struct {
-	Item1  *Item
-	Item2  *Item
-	Result *Item
+// parseSearchOptions reads the search form, the error is meant for the
+// client.
+func parseSearchOptions(r *http.Request) (searchOptions, error) {
+	opts := searchOptions{
+		Query:    strings.TrimSpace(r.FormValue("item")),
+		Category: r.FormValue("category"),
+	}
+	opts.Sort = r.FormValue("sort")
+	opts.MinLen, _ = strconv.Atoi(r.FormValue("minLen"))
+	opts.MaxLen, _ = strconv.Atoi(r.FormValue("maxLen"))
+	logrus.Debugf("Handling search for %+v", opts)
+
+	if len(opts.Query) > maxQueryLength {
+		return opts, fmt.Errorf("Search query too long, at most %d bytes allowed", maxQueryLength)
+	}
+	if !utf8.ValidString(opts.Query) {
+		return opts, errors.New("Search query is not valid UTF-8")
+	}
+	if _, ok := searchSortColumns[opts.Sort]; opts.Sort != "" && !ok {
+		return opts, errors.New("Invalid sort")
+	}
+	if opts.MinLen < 0 || opts.MaxLen < 0 || (opts.MaxLen > 0 && opts.MaxLen < opts.MinLen) {
+		return opts, errors.New("Invalid name length range")
+	}
+	return opts, nil
 }
 
-func searchItems(query string) ([]Item, bool, error) {
+// handleSearch renders the items matching the query. The query is trimmed,
+// queries longer than maxQueryLength or not valid UTF-8 are rejected with a
+// 400 and an empty search without any filter renders nothing instead of
+// matching every item.
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseSearchOptions(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Sort != "" && !requireMetrics(w) {
+		return
+	}
+	if opts.unfiltered() {
+		return
+	}
+	// Filters alone are a deliberate search, a too short query isn't
+	if n := utf8.RuneCountInString(opts.Query); n > 0 && n < minQueryLength {
+		if err := executeTemplate(w, "searchResults.html", searchResults{MinQueryLength: minQueryLength}); err != nil {
+			logrus.Errorf("Error executing template: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
 
-	limit := 1000
-	var items []Item
-	stmt, err := db.Prepare(`SELECT name, emoji, isNew FROM items WHERE name LIKE ? LIMIT ?`)
+	items, limited, err := searchItems(r.Context(), opts)
 	if err != nil {
-		return nil, false, err
+		logrus.Errorf("Error fetching items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
-	defer stmt.Close()
 
-	rows, err := stmt.Query("%"+query+"%", limit)
+	results := searchResults{Items: items, Limited: limited}
+	if r.FormValue("group") == "emoji" {
+		results.Groups = groupByEmoji(items)
+	}
+	if err = executeTemplate(w, "searchResults.html", results); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// APIItem is an item as the JSON API returns it, named like the game does.
+type APIItem struct {
+	Name       string `json:"name"`
+	Emoji      string `json:"emoji"`
+	Discovered bool   `json:"discovered"`
+}
+
+func newAPIItem(item *Item) APIItem {
+	return APIItem{Name: item.Name, Emoji: item.Emoji, Discovered: item.IsNew}
+}
+
+// searchFields are the item fields the search API can be limited to with
+// the fields parameter.
+var searchFields = map[string]func(APIItem) interface{}{
+	"name":       func(item APIItem) interface{} { return item.Name },
+	"emoji":      func(item APIItem) interface{} { return item.Emoji },
+	"discovered": func(item APIItem) interface{} { return item.Discovered },
+}
+
+// handleSearchAPI runs the same search as handleSearch and returns the items
+// as JSON. fields, a comma separated list, limits the items to those fields.
+func handleSearchAPI(w http.ResponseWriter, r *http.Request) {
+	opts, err := parseSearchOptions(r)
 	if err != nil {
-		return nil, false, err
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.Sort != "" && !requireMetrics(w) {
+		return
+	}
+	var fields []string
+	if f := r.FormValue("fields"); f != "" {
+		fields = strings.Split(f, ",")
+		for _, field := range fields {
+			if _, ok := searchFields[field]; !ok {
+				http.Error(w, fmt.Sprintf("Unknown field %q, use name, emoji or discovered", field), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if opts.unfiltered() {
+		http.Error(w, "Missing search query or filter", http.StatusBadRequest)
+		return
+	}
+	if n := utf8.RuneCountInString(opts.Query); n > 0 && n < minQueryLength {
+		http.Error(w, fmt.Sprintf("Search query too short, at least %d characters needed", minQueryLength), http.StatusBadRequest)
+		return
+	}
+
+	items, limited, err := searchItems(r.Context(), opts)
+	if err != nil {
+		logrus.Errorf("Error fetching items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	found := make([]APIItem, len(items))
+	for i := range items {
+		found[i] = newAPIItem(&items[i])
+	}
+	if fields == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Items   []APIItem `json:"items"`
+			Limited bool      `json:"limited"`
+		}{Items: found, Limited: limited})
+		return
+	}
+
+	projected := make([]map[string]interface{}, len(found))
+	for i, item := range found {
+		projected[i] = make(map[string]interface{}, len(fields))
+		for _, field := range fields {
+			projected[i][field] = searchFields[field](item)
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Items   []map[string]interface{} `json:"items"`
+		Limited bool                     `json:"limited"`
+	}{Items: projected, Limited: limited})
+}
+
+// searchResults is the data of searchResults.html. With Groups set, the
+// groups are shown instead of Items. MinQueryLength is set when the query was
+// too short to run.
+type searchResults struct {
+	Items          []Item
+	Groups         []EmojiGroup
+	Limited        bool
+	MinQueryLength int
+}
+
+// EmojiGroup is a run of search results sharing an emoji.
+type EmojiGroup struct {
+	Emoji string
+	Items []Item
+}
+
+// groupByEmoji groups items by emoji, keeping the groups in the order of
+// their best ranked item.
+func groupByEmoji(items []Item) []EmojiGroup {
+	groups := make([]EmojiGroup, 0)
+	index := make(map[string]int)
+	for _, item := range items {
+		i, ok := index[item.Emoji]
+		if !ok {
+			i = len(groups)
+			index[item.Emoji] = i
+			groups = append(groups, EmojiGroup{Emoji: item.Emoji})
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+	return groups
+}
+
+// handleEmoji lists the items with exactly the given emoji, or redirects to
+// the item page if there is only one. The path value is already unescaped, so
+// ZWJ sequences arrive whole.
+func handleEmoji(w http.ResponseWriter, r *http.Request) {
+	emoji := r.PathValue("emoji")
+	rows, err := db.QueryContext(r.Context(), `SELECT name, emoji, isNew FROM items WHERE emoji = ? ORDER BY name LIMIT ?`, emoji, searchLimit+1)
+	if err != nil {
+		logrus.Errorf("Error fetching items by emoji: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
 	defer rows.Close()
 
+	items := make([]Item, 0)
 	for rows.Next() {
 		var item Item
 		if err := rows.Scan(&item.Name, &item.Emoji, &item.IsNew); err != nil {
-			return nil, false, err
+			logrus.Errorf("Error fetching items by emoji: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
 		}
 		items = append(items, item)
 	}
+	limited := len(items) > searchLimit
+	if limited {
+		items = items[:searchLimit]
+	}
 
-	return items, len(items) == limit, nil
+	if len(items) == 1 {
+		http.Redirect(w, r, "/i/"+url.PathEscape(items[0].Name), http.StatusFound)
+		return
+	}
+
+	content := &bytes.Buffer{}
+	err = executeTemplate(content, "searchResults.html", searchResults{Items: items, Limited: limited})
+	if err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, fmt.Sprintf("%s | Infinite Craft Search", emoji), template.HTML(content.String()))
 }
 
-func getTotalItemCount() (int, error) {
-	var count int
-	row := db.QueryRow(`SELECT COUNT(*) FROM items`)
-	err := row.Scan(&count)
-	return count, err
+const browsePageSize = 100
+
+// browseLetters are the sections of the browse index, "#" collects every
+// item not starting with a latin letter.
+var browseLetters = strings.Split("ABCDEFGHIJKLMNOPQRSTUVWXYZ#", "")
+
+// handleBrowse lists the items starting with a letter, alphabetically and
+// paginated.
+func handleBrowse(w http.ResponseWriter, r *http.Request) {
+	letter := strings.ToUpper(r.URL.Query().Get("letter"))
+	if letter == "" {
+		letter = "A"
+	}
+	if !slices.Contains(browseLetters, letter) {
+		http.Error(w, "Invalid letter", http.StatusBadRequest)
+		return
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	items, hasNext, err := browseItems(letter, page)
+	if err != nil {
+		logrus.Errorf("Error browsing items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := struct {
+		Letters  []string
+		Letter   string
+		Items    []Item
+		Page     int
+		PrevPage int
+		NextPage int
+	}{Letters: browseLetters, Letter: letter, Items: items, Page: page, PrevPage: page - 1}
+	if hasNext {
+		data.NextPage = page + 1
+	}
+
+	content := &bytes.Buffer{}
+	err = executeTemplate(content, "browse.html", data)
+	if err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	renderStartPage(w, fmt.Sprintf("Browse %s | Infinite Craft Search", letter), template.HTML(content.String()))
+}
+
+const maxHubs = 500
+
+var hubsCache versionCache[[]Hub]
+
+// handleHubs lists the items with the highest total degree, the number of
+// recipes producing them plus the number of combinations using them.
+func handleHubs(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > maxHubs {
+		limit = 50
+	}
+
+	hubs, err := hubsCache.get(getHubs)
+	if err != nil {
+		logrus.Errorf("Error fetching hubs: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if len(hubs) > limit {
+		hubs = hubs[:limit]
+	}
+
+	content := &bytes.Buffer{}
+	if err := executeTemplate(content, "hubs.html", hubs); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, "Hubs | Infinite Craft Search", template.HTML(content.String()))
+}
+
+const maxRecipeCounts = 500
+
+var mostRecipesCache versionCache[[]RecipeCount]
+
+// handleMostRecipes lists the items with the most distinct recipes.
+func handleMostRecipes(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > maxRecipeCounts {
+		limit = 50
+	}
+
+	counts, err := mostRecipesCache.get(getMostRecipes)
+	if err != nil {
+		logrus.Errorf("Error fetching recipe counts: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if len(counts) > limit {
+		counts = counts[:limit]
+	}
+
+	content := &bytes.Buffer{}
+	if err := executeTemplate(content, "mostRecipes.html", counts); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, "Most Recipes | Infinite Craft Search", template.HTML(content.String()))
+}
+
+// RecipeCountBucket is the number of items with a given number of recipes.
+type RecipeCountBucket struct {
+	Recipes int `json:"recipes"`
+	Items   int `json:"items"`
+}
+
+var recipeCountDistributionCache versionCache[[]RecipeCountBucket]
+
+// handleRecipeCountDistribution serves how many items have each number of
+// recipes, for a histogram of the graph's shape.
+func handleRecipeCountDistribution(w http.ResponseWriter, r *http.Request) {
+	buckets, err := recipeCountDistributionCache.get(getRecipeCountDistribution)
+	if err != nil {
+		logrus.Errorf("Error fetching recipe count distribution: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(buckets)
+}
+
+// getRecipeCountDistribution groups the items by their recipe count. Items
+// without any recipe aren't counted.
+func getRecipeCountDistribution() ([]RecipeCountBucket, error) {
+	rows, err := db.Query(`SELECT recipes, COUNT(*)
+FROM (SELECT COUNT(*) AS recipes FROM combinations GROUP BY resultItem)
+GROUP BY recipes
+ORDER BY recipes`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	buckets := make([]RecipeCountBucket, 0)
+	for rows.Next() {
+		var b RecipeCountBucket
+		if err := rows.Scan(&b.Recipes, &b.Items); err != nil {
+			return nil, err
+		}
+		buckets = append(buckets, b)
+	}
+	return buckets, rows.Err()
+}
+
+// UpdatedItem is an item that gained a recipe after it was discovered, with
+// the time of the latest one. UpdatedAt is nil when that recipe was recorded
+// before the collector stored timestamps.
+type UpdatedItem struct {
+	Name      string     `json:"name"`
+	Emoji     string     `json:"emoji"`
+	Recipes   int        `json:"recipes"`
+	UpdatedAt *time.Time `json:"updatedAt"`
+}
+
+var recentlyUpdatedCache versionCache[[]UpdatedItem]
+
+// handleRecentlyUpdated lists the items whose recipes changed most recently.
+// Items with a single recipe are left out, they are new discoveries rather
+// than existing items gaining recipes.
+func handleRecentlyUpdated(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > maxRecipeCounts {
+		limit = 50
+	}
+
+	updated, err := recentlyUpdatedCache.get(getRecentlyUpdated)
+	if err != nil {
+		logrus.Errorf("Error fetching recently updated items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if len(updated) > limit {
+		updated = updated[:limit]
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updated)
+		return
+	}
+
+	content := &bytes.Buffer{}
+	if err := executeTemplate(content, "recentlyUpdated.html", updated); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, "Recently Updated | Infinite Craft Search", template.HTML(content.String()))
+}
+
+// getRecentlyUpdated returns the maxRecipeCounts items with more than one
+// recipe whose latest recipe was recorded last. Ids are handed out in
+// insertion order, so the highest id is the latest recipe even without
+// timestamps, and the resultItem index covers the grouping.
+func getRecentlyUpdated() ([]UpdatedItem, error) {
+	createdAt := "NULL"
+	if hasCreatedAt {
+		createdAt = "c.createdAt"
+	}
+	rows, err := db.Query(`SELECT i.name, i.emoji, latest.recipes, `+createdAt+`
+FROM (
+	SELECT resultItem, MAX(id) AS id, COUNT(*) AS recipes
+	FROM combinations
+	GROUP BY resultItem
+	HAVING COUNT(*) > 1
+) latest
+JOIN combinations c ON c.id = latest.id
+JOIN items i ON i.name = latest.resultItem
+ORDER BY latest.id DESC
+LIMIT ?`, maxRecipeCounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	updated := make([]UpdatedItem, 0)
+	for rows.Next() {
+		var item UpdatedItem
+		var t sql.NullTime
+		if err := rows.Scan(&item.Name, &item.Emoji, &item.Recipes, &t); err != nil {
+			return nil, err
+		}
+		if t.Valid {
+			item.UpdatedAt = &t.Time
+		}
+		updated = append(updated, item)
+	}
+	return updated, rows.Err()
+}
+
+// UniqueRecipe is an item with only one known recipe.
+type UniqueRecipe struct {
+	Name   string
+	Emoji  string
+	First  string
+	Second string
+}
+
+var uniqueRecipesCache versionCache[[]UniqueRecipe]
+
+// handleUniqueRecipe lists the items that can only be made one way, the
+// opposite end of the most-recipes leaderboard.
+func handleUniqueRecipe(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 500 {
+		limit = 100
+	}
+
+	unique, err := uniqueRecipesCache.get(getUniqueRecipes)
+	if err != nil {
+		logrus.Errorf("Error fetching unique recipes: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	start := min((page-1)*limit, len(unique))
+	end := min(start+limit, len(unique))
+	data := struct {
+		Items    []UniqueRecipe
+		Total    int
+		Page     int
+		Limit    int
+		PrevPage int
+		NextPage int
+	}{Items: unique[start:end], Total: len(unique), Page: page, Limit: limit, PrevPage: page - 1}
+	if end < len(unique) {
+		data.NextPage = page + 1
+	}
+
+	content := &bytes.Buffer{}
+	if err := executeTemplate(content, "uniqueRecipe.html", data); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, "Unique Recipes | Infinite Craft Search", template.HTML(content.String()))
+}
+
+func getUniqueRecipes() ([]UniqueRecipe, error) {
+	rows, err := db.Query(`SELECT items.name, items.emoji, MIN(combinations.firstItem), MIN(combinations.secondItem)
+FROM combinations
+JOIN items ON items.name = combinations.resultItem
+GROUP BY combinations.resultItem
+HAVING COUNT(*) = 1
+ORDER BY items.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unique := make([]UniqueRecipe, 0)
+	for rows.Next() {
+		var u UniqueRecipe
+		if err := rows.Scan(&u.Name, &u.Emoji, &u.First, &u.Second); err != nil {
+			return nil, err
+		}
+		unique = append(unique, u)
+	}
+	return unique, rows.Err()
+}
+
+const emojiStatsPageSize = 100
+
+// handleEmojiStats lists the emoji by how many items use them, as an HTML
+// table or as JSON with format=json.
+func handleEmojiStats(w http.ResponseWriter, r *http.Request) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT emoji, COUNT(*) FROM items
+GROUP BY emoji
+ORDER BY COUNT(*) DESC, emoji
+LIMIT ? OFFSET ?`, emojiStatsPageSize+1, (page-1)*emojiStatsPageSize)
+	if err != nil {
+		logrus.Errorf("Error fetching emoji stats: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	stats := make([]EmojiCount, 0, emojiStatsPageSize+1)
+	for rows.Next() {
+		var stat EmojiCount
+		if err := rows.Scan(&stat.Emoji, &stat.Items); err != nil {
+			logrus.Errorf("Error fetching emoji stats: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		stats = append(stats, stat)
+	}
+	hasNext := len(stats) > emojiStatsPageSize
+	if hasNext {
+		stats = stats[:emojiStatsPageSize]
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Page    int          `json:"page"`
+			HasNext bool         `json:"hasNext"`
+			Emoji   []EmojiCount `json:"emoji"`
+		}{Page: page, HasNext: hasNext, Emoji: stats})
+		return
+	}
+
+	data := struct {
+		Stats    []EmojiCount
+		Page     int
+		PrevPage int
+		NextPage int
+	}{Stats: stats, Page: page, PrevPage: page - 1}
+	if hasNext {
+		data.NextPage = page + 1
+	}
+
+	content := &bytes.Buffer{}
+	if err := executeTemplate(content, "emojiStats.html", data); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, "Emoji Stats | Infinite Craft Search", template.HTML(content.String()))
+}
+
+// Stats are dataset wide numbers shown on the stats page.
+type Stats struct {
+	Items            int    `json:"items"`
+	Combinations     int    `json:"combinations"`
+	SelfCombinations int    `json:"selfCombinations"`
+	DeepestItem      string `json:"deepestItem"`
+	MaxDepth         int    `json:"maxDepth"`
+	// Unreachable counts the items that can't be crafted from the base
+	// elements, SelfDependent the ones among them only blocked by needing
+	// themselves.
+	Unreachable   int `json:"unreachable"`
+	SelfDependent int `json:"selfDependent"`
+}
+
+var statsCache versionCache[Stats]
+
+func getStats() (Stats, error) {
+	var stats Stats
+	err := db.QueryRow(`SELECT
+	(SELECT COUNT(*) FROM items),
+	(SELECT COUNT(*) FROM combinations),
+	(SELECT COUNT(*) FROM combinations WHERE firstItem = secondItem)`).Scan(&stats.Items, &stats.Combinations, &stats.SelfCombinations)
+	if err != nil {
+		return stats, err
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		return stats, err
+	}
+	for name, d := range graph.depth {
+		if d > stats.MaxDepth || (d == stats.MaxDepth && d > 0 && name < stats.DeepestItem) {
+			stats.DeepestItem, stats.MaxDepth = name, d
+		}
+	}
+
+	names, err := itemNamesCache.get(getItemNames)
+	if err != nil {
+		return stats, err
+	}
+	for _, name := range names {
+		if _, ok := graph.depth[name]; !ok {
+			stats.Unreachable++
+		}
+	}
+	stats.SelfDependent = len(graph.selfDependent())
+	return stats, nil
+}
+
+// SelfDependentItem is an item that can't be crafted from the base elements
+// only because every way to it needs the item itself. Cycle is the steps
+// that make it again once it is owned, taking the craftable items it uses as
+// given.
+type SelfDependentItem struct {
+	Name  string       `json:"name"`
+	Cycle []recipeStep `json:"cycle"`
+}
+
+// handleSelfDependent lists the self-dependent items with the cycle keeping
+// each of them unreachable.
+func handleSelfDependent(w http.ResponseWriter, r *http.Request) {
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(graph.selfDependent())
+}
+
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	stats, err := statsCache.get(getStats)
+	if err != nil {
+		logrus.Errorf("Error fetching stats: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+		return
+	}
+
+	content := &bytes.Buffer{}
+	if err := executeTemplate(content, "stats.html", stats); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, "Stats | Infinite Craft Search", template.HTML(content.String()))
+}
+
+// handleSelfCombinations lists the combinations of an item with itself. The
+// collector only tries those with -allow-self, but imported data may have them.
+func handleSelfCombinations(w http.ResponseWriter, r *http.Request) {
+	rows, err := db.QueryContext(r.Context(), `SELECT id, firstItem, secondItem, resultItem FROM combinations
+WHERE firstItem = secondItem
+ORDER BY firstItem, id`)
+	if err != nil {
+		logrus.Errorf("Error fetching self combinations: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	combinations := make([]CombinationRow, 0)
+	for rows.Next() {
+		var c CombinationRow
+		if err := rows.Scan(&c.ID, &c.First, &c.Second, &c.Result); err != nil {
+			logrus.Errorf("Error fetching self combinations: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		combinations = append(combinations, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(combinations)
+}
+
+func handleItemCount(w http.ResponseWriter, r *http.Request) {
+	items, err := getTotalItemCount()
+	if err != nil {
+		http.Error(w, "Failed to get item count", http.StatusInternalServerError)
+		return
+	}
+	combinations, err := getTotalCombinationCount()
+	if err != nil {
+		http.Error(w, "Failed to get combination count", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Items        int `json:"items"`
+		Combinations int `json:"combinations"`
+	}{Items: items, Combinations: combinations})
+}
+
+// handleLocalStorage streams every item in the format the game imports, the
+// same file json.go writes. Emoji and isNew change without any new
+// combination, so the ETag is a hash of the items rather than a count.
+func handleLocalStorage(w http.ResponseWriter, r *http.Request) {
+	etag, err := itemsETag(r.Context())
+	if err != nil {
+		logrus.Errorf("Error hashing items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	setCacheControl(w)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT name, emoji, isNew FROM items ORDER BY name`)
+	if err != nil {
+		logrus.Errorf("Error fetching items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	// Once the first bytes are out errors can only be logged, the client
+	// sees a truncated file
+	w.Header().Set("Content-Type", "application/json")
+	bw := bufio.NewWriter(w)
+	bw.WriteString(`{"elements":[`)
+	for i := 0; rows.Next(); i++ {
+		var item struct {
+			Text       string `json:"text"`
+			Emoji      string `json:"emoji"`
+			Discovered bool   `json:"discovered"`
+		}
+		if err := rows.Scan(&item.Text, &item.Emoji, &item.Discovered); err != nil {
+			logrus.Errorf("Error streaming items: %v", err)
+			return
+		}
+		data, err := json.Marshal(item)
+		if err != nil {
+			logrus.Errorf("Error streaming items: %v", err)
+			return
+		}
+		if i > 0 {
+			bw.WriteByte(',')
+		}
+		bw.Write(data)
+	}
+	if err := rows.Err(); err != nil {
+		logrus.Errorf("Error streaming items: %v", err)
+		return
+	}
+	bw.WriteString(`]}`)
+	if err := bw.Flush(); err != nil {
+		logrus.Debugf("Error writing items: %v", err)
+	}
+}
+
+// itemsETag hashes the items handleLocalStorage streams, in the same order.
+// Reading them twice is cheaper than sending the file to a client that has it.
+func itemsETag(ctx context.Context) (string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, emoji, isNew FROM items ORDER BY name`)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	h := fnv.New64a()
+	for rows.Next() {
+		var name, emoji string
+		var isNew bool
+		if err := rows.Scan(&name, &emoji, &isNew); err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%s\x00%t\x00", name, emoji, isNew)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64()), nil
+}
+
+func handleItem(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	// Names merged away by mergeWhitespace.go redirect to the merged item
+	canonical, err := getCanonicalName(name)
+	if err != nil {
+		logrus.Errorf("Error fetching alias: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if canonical != "" {
+		http.Redirect(w, r, "/i/"+url.PathEscape(canonical), http.StatusMovedPermanently)
+		return
+	}
+
+	item, itemHTML, err := renderItem(r.Context(), name)
+	if err == errItemNotFound {
+		renderItemNotFound(w, name)
+		return
+	} else if err != nil {
+		logrus.Errorf("Error rendering item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	setCacheControl(w)
+	renderStartPage(w, fmt.Sprintf("%s | Infinite Craft Search", item.Name), itemHTML)
+}
+
+// setCacheControl lets clients and proxies cache a page for -cache-max-age.
+func setCacheControl(w http.ResponseWriter) {
+	if cacheMaxAge > 0 {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", cacheMaxAge))
+	}
+}
+
+// renderItemNotFound answers with a 404 page suggesting the existing items
+// with the closest names.
+func renderItemNotFound(w http.ResponseWriter, name string) {
+	logrus.Debugf("Item not found: %s", name)
+	suggestions, err := fuzzyMatches(name, maxSuggestions)
+	if err != nil {
+		logrus.Errorf("Error fetching suggestions: %v", err)
+	}
+
+	content := &bytes.Buffer{}
+	err = executeTemplate(content, "notFound.html", struct {
+		Name        string
+		Suggestions []string
+	}{Name: name, Suggestions: suggestions})
+	if err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	renderStartPage(w, "Not Found | Infinite Craft Search", template.HTML(content.String()))
+}
+
+// handleItemFragment serves only the item.html fragment, for swapping into
+// an already loaded page.
+func handleItemFragment(w http.ResponseWriter, r *http.Request) {
+	_, itemHTML, err := renderItem(r.Context(), r.PathValue("name"))
+	if err == errItemNotFound {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		logrus.Errorf("Error rendering item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	setCacheControl(w)
+	fmt.Fprint(w, itemHTML)
+}
+
+// APIRecipe is a recipe of an item as the JSON API returns it.
+type APIRecipe struct {
+	First       string `json:"first"`
+	FirstEmoji  string `json:"firstEmoji"`
+	Second      string `json:"second"`
+	SecondEmoji string `json:"secondEmoji"`
+}
+
+// handleItemAPI serves an item and every recipe producing it as JSON.
+func handleItemAPI(w http.ResponseWriter, r *http.Request) {
+	item, err := getItem(r.Context(), r.PathValue("name"))
+	if err != nil {
+		logrus.Errorf("Error fetching item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if item == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	combinations, err := getCombinations(r.Context(), item)
+	if err != nil {
+		logrus.Errorf("Error fetching combinations: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	recipes := make([]APIRecipe, len(combinations))
+	for i, c := range combinations {
+		recipes[i] = APIRecipe{First: c.Item1.Name, FirstEmoji: c.Item1.Emoji, Second: c.Item2.Name, SecondEmoji: c.Item2.Emoji}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		APIItem
+		Recipes []APIRecipe `json:"recipes"`
+	}{APIItem: newAPIItem(item), Recipes: recipes})
+}
+
+// handleCard serves a small self-contained card for an item, for previews
+// embedded in other pages.
+func handleCard(w http.ResponseWriter, r *http.Request) {
+	item, err := getItem(r.Context(), r.PathValue("name"))
+	if err != nil {
+		logrus.Errorf("Error fetching item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if item == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	var recipes int
+	if err := db.QueryRowContext(r.Context(), `SELECT COUNT(*) FROM combinations WHERE resultItem = ?`, item.Name).Scan(&recipes); err != nil {
+		logrus.Errorf("Error fetching recipe count: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	depth, reachable := graph.depth[item.Name]
+
+	setCacheControl(w)
+	err = executeTemplate(w, "card.html", struct {
+		Item      *Item
+		Depth     int
+		Reachable bool
+		Recipes   int
+	}{Item: item, Depth: depth, Reachable: reachable, Recipes: recipes})
+	if err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+var errItemNotFound = errors.New("item not found")
+
+// renderItem looks up an item with its combinations and renders item.html.
+// It returns errItemNotFound if there is no such item. Rendered pages are
+// kept in itemPages.
+func renderItem(ctx context.Context, name string) (*Item, template.HTML, error) {
+	version, err := getDataVersion()
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching data version: %w", err)
+	}
+	if cached, itemHTML, ok := itemPages.get(name, version); ok {
+		// An emoji can be fixed without a new combination, e.g. by
+		// repairEmoji.go, so the item itself is checked on every hit
+		item, err := getItem(ctx, name)
+		if err != nil {
+			return nil, "", fmt.Errorf("fetching item: %w", err)
+		}
+		if item != nil && *item == *cached {
+			return cached, itemHTML, nil
+		}
+	}
+
+	item, itemHTML, err := renderItemUncached(ctx, name)
+	if err != nil {
+		return nil, "", err
+	}
+	itemPages.add(name, version, item, itemHTML)
+	return item, itemHTML, nil
+}
+
+func renderItemUncached(ctx context.Context, name string) (*Item, template.HTML, error) {
+	item, err := getItem(ctx, name)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching item: %w", err)
+	}
+
+	if item == nil {
+		return nil, "", errItemNotFound
+	}
+
+	combinations, err := getCombinations(ctx, item)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching combinations: %w", err)
+	}
+
+	related, err := getRelatedItems(item.Name)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching related items: %w", err)
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		return nil, "", fmt.Errorf("loading recipe graph: %w", err)
+	}
+	complexity, reachable := graph.ancestorCount(item.Name)
+
+	history, err := getHistory(ctx, item.Name)
+	if err != nil {
+		return nil, "", fmt.Errorf("fetching history: %w", err)
+	}
+
+	tempWriter := &bytes.Buffer{}
+	err = executeTemplate(tempWriter, "item.html", struct {
+		Item         *Item
+		Combinations []Combination
+		Related      []RelatedItem
+		Reachable    bool
+		Complexity   int
+		History      []HistoryEntry
+	}{Item: item, Combinations: combinations, Related: related, Reachable: reachable, Complexity: complexity, History: history})
+	if err != nil {
+		return nil, "", fmt.Errorf("executing template: %w", err)
+	}
+	return item, template.HTML(tempWriter.String()), nil
+}
+
+// handleCombinationsAPI pages through all combinations ordered by id. The id
+// of the last returned row is handed out as nextCursor, so rows inserted by a
+// running crawl never shift pages the way an offset would.
+func handleCombinationsAPI(w http.ResponseWriter, r *http.Request) {
+	cursor, err := strconv.ParseInt(r.URL.Query().Get("cursor"), 10, 64)
+	if err != nil {
+		cursor = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 10000 {
+		limit = 1000
+	}
+
+	rows, nextCursor, err := getCombinationsPage(cursor, limit)
+	if err != nil {
+		logrus.Errorf("Error fetching combinations page: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Combinations []CombinationRow `json:"combinations"`
+		NextCursor   *int64           `json:"nextCursor"`
+	}{Combinations: rows, NextCursor: nextCursor})
+}
+
+// IngredientUse is a combination an item is an ingredient of, seen from that
+// item.
+type IngredientUse struct {
+	ID      int64  `json:"id"`
+	Partner string `json:"partner"`
+	Result  string `json:"result"`
+}
+
+// handleCombinationsByIngredient pages through the combinations using an item
+// as either ingredient, ordered by result.
+func handleCombinationsByIngredient(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 10000 {
+		limit = 1000
+	}
+
+	item, err := getItem(r.Context(), name)
+	if err != nil {
+		logrus.Errorf("Error fetching item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if item == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT id, CASE WHEN firstItem = ? THEN secondItem ELSE firstItem END, resultItem
+FROM combinations
+WHERE firstItem = ? OR secondItem = ?
+ORDER BY resultItem, id
+LIMIT ? OFFSET ?`, name, name, name, limit+1, offset)
+	if err != nil {
+		logrus.Errorf("Error fetching combinations by ingredient: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	uses := make([]IngredientUse, 0)
+	for rows.Next() {
+		var use IngredientUse
+		if err := rows.Scan(&use.ID, &use.Partner, &use.Result); err != nil {
+			logrus.Errorf("Error fetching combinations by ingredient: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		uses = append(uses, use)
+	}
+
+	var nextOffset *int
+	if len(uses) > limit {
+		uses = uses[:limit]
+		next := offset + limit
+		nextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Item         string          `json:"item"`
+		Combinations []IngredientUse `json:"combinations"`
+		NextOffset   *int            `json:"nextOffset"`
+	}{Item: name, Combinations: uses, NextOffset: nextOffset})
+}
+
+// RecipeMatch is a combination found by its result, with the emoji of both
+// ingredients.
+type RecipeMatch struct {
+	ID          int64  `json:"id"`
+	First       string `json:"first"`
+	FirstEmoji  string `json:"firstEmoji"`
+	Second      string `json:"second"`
+	SecondEmoji string `json:"secondEmoji"`
+	Result      string `json:"result"`
+}
+
+// handleCombinationsSearch pages through the combinations whose result
+// contains the result parameter, ordered by result.
+func handleCombinationsSearch(w http.ResponseWriter, r *http.Request) {
+	result := r.URL.Query().Get("result")
+	if result == "" {
+		http.Error(w, "Missing result parameter", http.StatusBadRequest)
+		return
+	}
+	if len(result) > maxQueryLength {
+		http.Error(w, fmt.Sprintf("Search query too long, at most %d bytes allowed", maxQueryLength), http.StatusBadRequest)
+		return
+	}
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	rows, err := db.QueryContext(r.Context(), `SELECT c.id, c.firstItem, COALESCE(f.emoji, ''), c.secondItem, COALESCE(s.emoji, ''), c.resultItem
+FROM combinations c
+LEFT JOIN items f ON f.name = c.firstItem
+LEFT JOIN items s ON s.name = c.secondItem
+WHERE c.resultItem LIKE ? ESCAPE '\'
+ORDER BY c.resultItem, c.id
+LIMIT ? OFFSET ?`, "%"+escapeLike(result)+"%", limit+1, offset)
+	if err != nil {
+		logrus.Errorf("Error searching combinations: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	matches := make([]RecipeMatch, 0)
+	for rows.Next() {
+		var m RecipeMatch
+		if err := rows.Scan(&m.ID, &m.First, &m.FirstEmoji, &m.Second, &m.SecondEmoji, &m.Result); err != nil {
+			logrus.Errorf("Error searching combinations: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		matches = append(matches, m)
+	}
+
+	var nextOffset *int
+	if len(matches) > limit {
+		matches = matches[:limit]
+		next := offset + limit
+		nextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Combinations []RecipeMatch `json:"combinations"`
+		NextOffset   *int          `json:"nextOffset"`
+	}{Combinations: matches, NextOffset: nextOffset})
+}
+
+// maxOwnedItems caps the inventory accepted by /api/craftable.
+const maxOwnedItems = 1000
+
+// CraftableItem is an item that can be made from an inventory, with one
+// recipe for it.
+type CraftableItem struct {
+	Name   string `json:"name"`
+	Emoji  string `json:"emoji"`
+	First  string `json:"first"`
+	Second string `json:"second"`
+}
+
+// handleCraftable takes a JSON list of owned items and pages through the new
+// items one combination of two owned items away, ordered by name.
+func handleCraftable(w http.ResponseWriter, r *http.Request) {
+	offset, err := strconv.Atoi(r.URL.Query().Get("offset"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	var owned []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&owned); err != nil {
+		http.Error(w, "Expected a JSON list of item names", http.StatusBadRequest)
+		return
+	}
+	if len(owned) == 0 {
+		http.Error(w, "No items given", http.StatusBadRequest)
+		return
+	}
+	if len(owned) > maxOwnedItems {
+		http.Error(w, fmt.Sprintf("At most %d items are accepted", maxOwnedItems), http.StatusBadRequest)
+		return
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(owned)), ",")
+	args := make([]interface{}, 0, 3*len(owned)+2)
+	for i := 0; i < 3; i++ {
+		for _, name := range owned {
+			args = append(args, name)
+		}
+	}
+	args = append(args, limit+1, offset)
+
+	rows, err := db.QueryContext(r.Context(), `SELECT c.resultItem, i.emoji, c.firstItem, c.secondItem
+FROM combinations c
+JOIN items i ON i.name = c.resultItem
+WHERE c.id IN (
+	SELECT MIN(id) FROM combinations
+	WHERE firstItem IN (`+placeholders+`)
+		AND secondItem IN (`+placeholders+`)
+		AND resultItem NOT IN (`+placeholders+`)
+	GROUP BY resultItem
+)
+ORDER BY c.resultItem
+LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		logrus.Errorf("Error fetching craftable items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	craftable := make([]CraftableItem, 0)
+	for rows.Next() {
+		var item CraftableItem
+		if err := rows.Scan(&item.Name, &item.Emoji, &item.First, &item.Second); err != nil {
+			logrus.Errorf("Error fetching craftable items: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		craftable = append(craftable, item)
+	}
+
+	var nextOffset *int
+	if len(craftable) > limit {
+		craftable = craftable[:limit]
+		next := offset + limit
+		nextOffset = &next
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Items      []CraftableItem `json:"items"`
+		NextOffset *int            `json:"nextOffset"`
+	}{Items: craftable, NextOffset: nextOffset})
+}
+
+// craftAPIURL is the game API the collector crawls.
+const craftAPIURL = "https://neal.fun/api/infinite-craft/pair"
+
+// contributeInterval is the least time between two game API calls made for
+// /api/contribute.
+const contributeInterval = 2 * time.Second
+
+var (
+	// contributeMu serializes contributions, so a pair sent twice at once
+	// only calls the API once.
+	contributeMu   sync.Mutex
+	lastContribute time.Time
+)
+
+// craftResponse is the game API response, see ApiResponse in collectData.go.
+type craftResponse struct {
+	Result string `json:"result"`
+	Emoji  string `json:"emoji"`
+	IsNew  bool   `json:"isNew"`
+}
+
+// handleContribute looks up the result of a pair with the game API and stores
+// it like the collector does. Pairs already known are answered from the
+// database without calling the API.
+func handleContribute(w http.ResponseWriter, r *http.Request) {
+	var pair struct {
+		First  string `json:"first"`
+		Second string `json:"second"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&pair); err != nil || pair.First == "" || pair.Second == "" {
+		http.Error(w, "Expected a JSON object with first and second", http.StatusBadRequest)
+		return
+	}
+	for _, name := range []string{pair.First, pair.Second} {
+		item, err := getItem(r.Context(), name)
+		if err != nil {
+			logrus.Errorf("Error fetching item: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if item == nil {
+			http.Error(w, "Unknown item: "+name, http.StatusBadRequest)
+			return
+		}
+	}
+
+	contributeMu.Lock()
+	defer contributeMu.Unlock()
+
+	result, err := getPairResult(r.Context(), pair.First, pair.Second)
+	if err != nil {
+		logrus.Errorf("Error fetching combination: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	isNew := result == ""
+	if isNew {
+		if wait := contributeInterval - time.Since(lastContribute); wait > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+			return
+		}
+		lastContribute = time.Now()
+
+		response, err := callCraftAPI(r.Context(), pair.First, pair.Second)
+		if err != nil {
+			logrus.Errorf("Error calling the game API: %v", err)
+			http.Error(w, "Bad Gateway", http.StatusBadGateway)
+			return
+		}
+		if err := storeContribution(pair.First, pair.Second, response); err != nil {
+			logrus.Errorf("Error storing contribution: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		logrus.Infof("Contributed %s + %s = %s", pair.First, pair.Second, response.Result)
+		result = response.Result
+	}
+
+	item, err := getItem(r.Context(), result)
+	if err != nil || item == nil {
+		logrus.Errorf("Error fetching item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Result     string `json:"result"`
+		Emoji      string `json:"emoji"`
+		Discovered bool   `json:"discovered"`
+		Added      bool   `json:"added"`
+	}{Result: item.Name, Emoji: item.Emoji, Discovered: item.IsNew, Added: isNew})
+}
+
+// getPairResult returns the known result of a pair in either order, or "".
+func getPairResult(ctx context.Context, first, second string) (string, error) {
+	var result string
+	err := db.QueryRowContext(ctx, `SELECT resultItem FROM combinations
+WHERE (firstItem = ? AND secondItem = ?) OR (firstItem = ? AND secondItem = ?)
+LIMIT 1`, first, second, second, first).Scan(&result)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return result, err
+}
+
+// callCraftAPI makes the same request as callApi in collectData.go, without
+// its retries.
+func callCraftAPI(ctx context.Context, first, second string) (*craftResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", craftAPIURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	q := req.URL.Query()
+	q.Add("first", first)
+	q.Add("second", second)
+	req.URL.RawQuery = q.Encode()
+
+	req.Header.Add("referer", "https://neal.fun/infinite-craft/")
+	req.Header.Add("user-agent", "InfiniteCraft_Mapper/rate-limited")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("API request failed with status code: %d", resp.StatusCode)
+	}
+
+	var response craftResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return nil, err
+	}
+	if response.Result == "" {
+		return nil, errors.New("API returned no result")
+	}
+	return &response, nil
+}
+
+// storeContribution writes an API result with the same upsert and insert as
+// the collector.
+func storeContribution(first, second string, response *craftResponse) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("INSERT INTO items (name, emoji, isNew) VALUES (?, ?, ?) ON CONFLICT(name) DO UPDATE SET emoji=excluded.emoji, isNew=(isNew OR excluded.isNew)", response.Result, response.Emoji, response.IsNew)
+	if err != nil {
+		return err
+	}
+	if hasCreatedAt {
+		_, err = tx.Exec("INSERT INTO combinations (firstItem, secondItem, resultItem, createdAt) VALUES (?, ?, ?, ?)", first, second, response.Result, time.Now().UTC())
+	} else {
+		_, err = tx.Exec("INSERT INTO combinations (firstItem, secondItem, resultItem) VALUES (?, ?, ?)", first, second, response.Result)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// maxMetricsBatch caps the items accepted by /api/metrics/batch.
+const maxMetricsBatch = 500
+
+// ItemMetrics are the numbers /api/metrics/batch returns for an item. Depth is
+// nil for items that can't be crafted from the base elements.
+type ItemMetrics struct {
+	Name    string `json:"name"`
+	Depth   *int   `json:"depth"`
+	Recipes int    `json:"recipes"`
+	Uses    int    `json:"uses"`
+}
+
+// handleMetricsBatch takes a JSON list of item names and returns the depth,
+// recipe count and usage count of each known item, in the order given.
+func handleMetricsBatch(w http.ResponseWriter, r *http.Request) {
+	var names []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&names); err != nil {
+		http.Error(w, "Expected a JSON list of item names", http.StatusBadRequest)
+		return
+	}
+	if len(names) > maxMetricsBatch {
+		http.Error(w, fmt.Sprintf("At most %d items are accepted", maxMetricsBatch), http.StatusBadRequest)
+		return
+	}
+
+	metrics := make([]ItemMetrics, 0, len(names))
+	if len(names) == 0 {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(metrics)
+		return
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(names)), ",")
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+	// Ingredients are counted once per combination, so A + A is one use
+	rows, err := db.QueryContext(r.Context(), `SELECT items.name,
+	(SELECT COUNT(*) FROM combinations WHERE resultItem = items.name),
+	(SELECT COUNT(*) FROM combinations WHERE firstItem = items.name OR secondItem = items.name)
+FROM items
+WHERE items.name IN (`+placeholders+`)`, args...)
+	if err != nil {
+		logrus.Errorf("Error fetching item metrics: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	found := make(map[string]ItemMetrics)
+	for rows.Next() {
+		var m ItemMetrics
+		if err := rows.Scan(&m.Name, &m.Recipes, &m.Uses); err != nil {
+			logrus.Errorf("Error fetching item metrics: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if d, ok := graph.depth[m.Name]; ok {
+			m.Depth = &d
+		}
+		found[m.Name] = m
+	}
+
+	for _, name := range names {
+		if m, ok := found[name]; ok {
+			metrics = append(metrics, m)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
+
+// handlePlan takes a target and a list of owned items and returns the steps to
+// craft the target, using owned items wherever that is shorter.
+func handlePlan(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		Target string   `json:"target"`
+		Owned  []string `json:"owned"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&request); err != nil || request.Target == "" {
+		http.Error(w, "Expected a JSON object with target and owned", http.StatusBadRequest)
+		return
+	}
+	if len(request.Owned) > maxOwnedItems {
+		http.Error(w, fmt.Sprintf("At most %d items are accepted", maxOwnedItems), http.StatusBadRequest)
+		return
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	steps, ok := graph.plan(request.Target, request.Owned)
+	if !ok {
+		http.Error(w, "No recipe found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Target string       `json:"target"`
+		Steps  []recipeStep `json:"steps"`
+	}{Target: request.Target, Steps: steps})
+}
+
+// handleAttempted answers whether the pair a and b was already tried, in
+// either order. Every pair the collector tries is recorded as a combination,
+// pairs that make nothing with the game's Nothing result, so there is no
+// separate table of attempted pairs.
+func handleAttempted(w http.ResponseWriter, r *http.Request) {
+	a, b := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if a == "" || b == "" {
+		http.Error(w, "Missing a or b", http.StatusBadRequest)
+		return
+	}
+
+	result, err := getPairResult(r.Context(), a, b)
+	if err != nil {
+		logrus.Errorf("Error fetching pair result: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var known *string
+	if result != "" {
+		known = &result
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Attempted bool    `json:"attempted"`
+		Result    *string `json:"result"`
+	}{Attempted: known != nil, Result: known})
+}
+
+// maxChainItems caps the items accepted by /api/chain.
+const maxChainItems = 100
+
+// handleChain takes a JSON list of items and combines them left to right,
+// the first two and then each result with the next item. It stops at the
+// first pair with no known result and reports it as missing.
+func handleChain(w http.ResponseWriter, r *http.Request) {
+	var chain []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&chain); err != nil {
+		http.Error(w, "Expected a JSON list of item names", http.StatusBadRequest)
+		return
+	}
+	if len(chain) < 2 {
+		http.Error(w, "At least two items are needed", http.StatusBadRequest)
+		return
+	}
+	if len(chain) > maxChainItems {
+		http.Error(w, fmt.Sprintf("At most %d items are accepted", maxChainItems), http.StatusBadRequest)
+		return
+	}
+
+	steps := make([]recipeStep, 0, len(chain)-1)
+	var missing *recipeStep
+	current := chain[0]
+	for _, next := range chain[1:] {
+		result, err := getPairResult(r.Context(), current, next)
+		if err != nil {
+			logrus.Errorf("Error fetching pair result: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if result == "" {
+			missing = &recipeStep{First: current, Second: next}
+			break
+		}
+		steps = append(steps, recipeStep{First: current, Second: next, Result: result})
+		current = result
+	}
+
+	// Result is the end of the chain, or the last known result before the
+	// missing pair
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Steps    []recipeStep `json:"steps"`
+		Complete bool         `json:"complete"`
+		Result   string       `json:"result"`
+		Missing  *recipeStep  `json:"missing"`
+	}{Steps: steps, Complete: missing == nil, Result: current, Missing: missing})
+}
+
+// maxGoalDepth bounds how many rounds of crafting away from an inventory
+// /api/next-goals looks, the number of candidates grows quickly with it.
+const maxGoalDepth = 3
+
+// Goal is an item not owned yet, with the fewest combinations that make it
+// from an inventory.
+type Goal struct {
+	Name  string       `json:"name"`
+	Emoji string       `json:"emoji"`
+	Steps []recipeStep `json:"steps"`
+}
+
+// handleNextGoals takes a JSON list of owned items and suggests the items
+// they are closest to, ranked by the number of combinations still needed.
+func handleNextGoals(w http.ResponseWriter, r *http.Request) {
+	depth, err := strconv.Atoi(r.URL.Query().Get("depth"))
+	if err != nil || depth <= 0 || depth > maxGoalDepth {
+		depth = 2
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var owned []string
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&owned); err != nil {
+		http.Error(w, "Expected a JSON list of item names", http.StatusBadRequest)
+		return
+	}
+	if len(owned) > maxOwnedItems {
+		http.Error(w, fmt.Sprintf("At most %d items are accepted", maxOwnedItems), http.StatusBadRequest)
+		return
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	goals := graph.nextGoals(owned, depth)
+	if len(goals) > limit {
+		goals = goals[:limit]
+	}
+
+	if len(goals) > 0 {
+		args := make([]interface{}, len(goals))
+		index := make(map[string]int)
+		for i, goal := range goals {
+			args[i] = goal.Name
+			index[goal.Name] = i
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+		rows, err := db.QueryContext(r.Context(), `SELECT name, emoji FROM items WHERE name IN (`+placeholders+`)`, args...)
+		if err != nil {
+			logrus.Errorf("Error fetching goal emoji: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var name, emoji string
+			if err := rows.Scan(&name, &emoji); err != nil {
+				logrus.Errorf("Error fetching goal emoji: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			goals[index[name]].Emoji = emoji
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Goals []Goal `json:"goals"`
+	}{Goals: goals})
+}
+
+// mixCandidates is how many fuzzy matches of each name /api/suggest-mix
+// combines.
+const mixCandidates = 5
+
+// MixResult is a known combination, with the emoji of its result.
+type MixResult struct {
+	First  string `json:"first"`
+	Second string `json:"second"`
+	Result string `json:"result"`
+	Emoji  string `json:"emoji"`
+}
+
+// handleSuggestMix answers what a and b combine into. Without an exact match
+// it falls back to the known combinations of the items closest to a and b.
+func handleSuggestMix(w http.ResponseWriter, r *http.Request) {
+	a, b := r.URL.Query().Get("a"), r.URL.Query().Get("b")
+	if a == "" || b == "" {
+		http.Error(w, "Missing a or b", http.StatusBadRequest)
+		return
+	}
+
+	results, err := getMixResults(r.Context(), []string{a}, []string{b})
+	if err != nil {
+		logrus.Errorf("Error fetching mix: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	exact := len(results) > 0
+
+	if !exact {
+		candidatesA, err := fuzzyMatches(a, mixCandidates)
+		if err != nil {
+			logrus.Errorf("Error fetching suggestions: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		candidatesB, err := fuzzyMatches(b, mixCandidates)
+		if err != nil {
+			logrus.Errorf("Error fetching suggestions: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if len(candidatesA) > 0 && len(candidatesB) > 0 {
+			results, err = getMixResults(r.Context(), candidatesA, candidatesB)
+			if err != nil {
+				logrus.Errorf("Error fetching mix: %v", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Exact   bool        `json:"exact"`
+		Results []MixResult `json:"results"`
+	}{Exact: exact, Results: results})
+}
+
+// getMixResults returns the combinations of any item in as with any item in
+// bs, in either order.
+func getMixResults(ctx context.Context, as, bs []string) ([]MixResult, error) {
+	placeholdersA := strings.TrimSuffix(strings.Repeat("?,", len(as)), ",")
+	placeholdersB := strings.TrimSuffix(strings.Repeat("?,", len(bs)), ",")
+	args := make([]interface{}, 0, 2*(len(as)+len(bs)))
+	for _, names := range [][]string{as, bs, bs, as} {
+		for _, name := range names {
+			args = append(args, name)
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT combinations.firstItem, combinations.secondItem, combinations.resultItem, items.emoji
+FROM combinations
+JOIN items ON items.name = combinations.resultItem
+WHERE (firstItem IN (`+placeholdersA+`) AND secondItem IN (`+placeholdersB+`))
+	OR (firstItem IN (`+placeholdersB+`) AND secondItem IN (`+placeholdersA+`))
+ORDER BY combinations.id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := make([]MixResult, 0)
+	for rows.Next() {
+		var result MixResult
+		if err := rows.Scan(&result.First, &result.Second, &result.Result, &result.Emoji); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, rows.Err()
+}
+
+// handleRecipe serves the shortest build order for an item, starting from the
+// base elements.
+func handleRecipe(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	explain := false
+	if value := r.URL.Query().Get("explain"); value != "" {
+		var err error
+		if explain, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "Invalid explain, use true or false", http.StatusBadRequest)
+			return
+		}
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	steps, ok := graph.shortestRecipe(name)
+	if !ok {
+		http.Error(w, "No recipe found", http.StatusNotFound)
+		return
+	}
+
+	if r.URL.Query().Get("format") == "compact" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, encodeCompactRecipe(steps))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !explain {
+		json.NewEncoder(w).Encode(struct {
+			Item  string       `json:"item"`
+			Depth int          `json:"depth"`
+			Steps []recipeStep `json:"steps"`
+		}{Item: name, Depth: graph.depth[name], Steps: steps})
+		return
+	}
+
+	explained := make([]explainedStep, len(steps))
+	for i, step := range steps {
+		explained[i] = graph.explainStep(step)
+	}
+	json.NewEncoder(w).Encode(struct {
+		Item  string          `json:"item"`
+		Depth int             `json:"depth"`
+		Steps []explainedStep `json:"steps"`
+	}{Item: name, Depth: graph.depth[name], Steps: explained})
+}
+
+// encodeCompactRecipe writes a build order as text to share, one
+// "First + Second = Result" line per step.
+func encodeCompactRecipe(steps []recipeStep) string {
+	var b strings.Builder
+	for _, step := range steps {
+		fmt.Fprintf(&b, "%s + %s = %s\n", step.First, step.Second, step.Result)
+	}
+	return b.String()
+}
+
+// decodeCompactRecipe parses the output of encodeCompactRecipe. Names can
+// contain " + " and " = " themselves, so each line is split where both
+// ingredients are base elements or results of earlier lines.
+func decodeCompactRecipe(text string) ([]recipeStep, error) {
+	known := make(map[string]bool)
+	for _, base := range baseElements {
+		known[base] = true
+	}
+	steps := make([]recipeStep, 0)
+	for i, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		step, ok := splitCompactStep(line, known)
+		if !ok {
+			return nil, fmt.Errorf("line %d: %q isn't a step using known items", i+1, line)
+		}
+		known[step.Result] = true
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func splitCompactStep(line string, known map[string]bool) (recipeStep, bool) {
+	for plus := strings.Index(line, " + "); plus >= 0; plus = nextIndex(line, " + ", plus) {
+		if !known[line[:plus]] {
+			continue
+		}
+		rest := line[plus+3:]
+		for equals := strings.Index(rest, " = "); equals >= 0; equals = nextIndex(rest, " = ", equals) {
+			if known[rest[:equals]] {
+				return recipeStep{First: line[:plus], Second: rest[:equals], Result: rest[equals+3:]}, true
+			}
+		}
+	}
+	return recipeStep{}, false
+}
+
+// nextIndex returns the index of the next sep in s after the one at i, or -1.
+func nextIndex(s, sep string, i int) int {
+	next := strings.Index(s[i+1:], sep)
+	if next < 0 {
+		return -1
+	}
+	return i + 1 + next
+}
+
+// handleDecodeRecipe takes a recipe shared with /recipe/{name}?format=compact
+// and returns its steps, with the ones missing from the dataset listed by
+// their 0-based index.
+func handleDecodeRecipe(w http.ResponseWriter, r *http.Request) {
+	text, err := io.ReadAll(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "Recipe too large", http.StatusBadRequest)
+		return
+	}
+	steps, err := decodeCompactRecipe(string(text))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// The cached graph keeps a long recipe from costing a query per step
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	unknown := make([]int, 0)
+	for i, step := range steps {
+		if !graph.hasRecipe(step) {
+			unknown = append(unknown, i)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Steps   []recipeStep `json:"steps"`
+		Unknown []int        `json:"unknown"`
+	}{Steps: steps, Unknown: unknown})
+}
+
+// explainedStep is a recipe step annotated for the explain option of
+// /recipe/{name}.
+type explainedStep struct {
+	recipeStep
+	Explanation stepExplanation `json:"explanation"`
+}
+
+type stepExplanation struct {
+	FirstIsBase  bool   `json:"firstIsBase"`
+	SecondIsBase bool   `json:"secondIsBase"`
+	Depth        int    `json:"depth"`
+	Text         string `json:"text"`
+}
+
+func (g *recipeGraph) explainStep(step recipeStep) explainedStep {
+	e := stepExplanation{
+		FirstIsBase:  isBaseElement(step.First),
+		SecondIsBase: isBaseElement(step.Second),
+		Depth:        g.depth[step.Result],
+	}
+	describe := func(name string, base bool) string {
+		if base {
+			return "base element " + name
+		}
+		return fmt.Sprintf("%s (depth %d)", name, g.depth[name])
+	}
+	e.Text = fmt.Sprintf("Combine %s with %s to make %s at depth %d",
+		describe(step.First, e.FirstIsBase), describe(step.Second, e.SecondIsBase), step.Result, e.Depth)
+	return explainedStep{recipeStep: step, Explanation: e}
+}
+
+// TreeMatch is an ancestor matching a /api/tree-contains query, with the
+// 1-based step of the build order that first uses or makes it.
+type TreeMatch struct {
+	Name string `json:"name"`
+	Step int    `json:"step"`
+}
+
+// handleTreeContains searches the items in an item's build order for names
+// containing q, ignoring case.
+func handleTreeContains(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	q := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("q")))
+	if q == "" {
+		http.Error(w, "Missing q", http.StatusBadRequest)
+		return
+	}
+	if len(q) > maxQueryLength {
+		http.Error(w, fmt.Sprintf("Search query too long, at most %d bytes allowed", maxQueryLength), http.StatusBadRequest)
+		return
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// The build order visits every ancestor once, so it is bounded by the
+	// number of items and can't loop
+	steps, ok := graph.shortestRecipe(name)
+	if !ok {
+		http.Error(w, "No recipe found", http.StatusNotFound)
+		return
+	}
+
+	matches := make([]TreeMatch, 0)
+	seen := make(map[string]bool)
+	for i, step := range steps {
+		for _, ancestor := range []string{step.First, step.Second, step.Result} {
+			if ancestor == name || seen[ancestor] {
+				continue
+			}
+			seen[ancestor] = true
+			if strings.Contains(strings.ToLower(ancestor), q) {
+				matches = append(matches, TreeMatch{Name: ancestor, Step: i + 1})
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Item    string      `json:"item"`
+		Steps   int         `json:"steps"`
+		Matches []TreeMatch `json:"matches"`
+	}{Item: name, Steps: len(steps), Matches: matches})
+}
+
+// HistoryEntry is a recipe for an item with the time it was recorded, which
+// is nil for recipes recorded before the collector stored timestamps.
+type HistoryEntry struct {
+	First     string     `json:"first"`
+	Second    string     `json:"second"`
+	CreatedAt *time.Time `json:"createdAt"`
+}
+
+// getHistory returns the recipes for an item in the order they were found.
+func getHistory(ctx context.Context, name string) ([]HistoryEntry, error) {
+	createdAt := "NULL"
+	if hasCreatedAt {
+		createdAt = "createdAt"
+	}
+	// Ids are handed out in insertion order, so they order the recipes
+	// without timestamps too
+	rows, err := db.QueryContext(ctx, `SELECT firstItem, secondItem, `+createdAt+` FROM combinations WHERE resultItem = ? ORDER BY id`, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]HistoryEntry, 0)
+	for rows.Next() {
+		var entry HistoryEntry
+		var t sql.NullTime
+		if err := rows.Scan(&entry.First, &entry.Second, &t); err != nil {
+			return nil, err
+		}
+		if t.Valid {
+			entry.CreatedAt = &t.Time
+		}
+		history = append(history, entry)
+	}
+	return history, rows.Err()
+}
+
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	item, err := getItem(r.Context(), name)
+	if err != nil {
+		logrus.Errorf("Error fetching item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if item == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	history, err := getHistory(r.Context(), item.Name)
+	if err != nil {
+		logrus.Errorf("Error fetching history: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Item    string         `json:"item"`
+		History []HistoryEntry `json:"history"`
+	}{Item: item.Name, History: history})
+}
+
+const (
+	// maxTreeDepth and maxTreeNodes bound the drawn recipe tree, deeper or
+	// further ingredients are drawn as a truncated node.
+	maxTreeDepth = 8
+	maxTreeNodes = 255
+
+	treeNodeWidth   = 140
+	treeNodeHeight  = 44
+	treeLevelHeight = 80
+)
+
+// treeNode is an item in a drawn recipe tree, x is in leaf slots.
+type treeNode struct {
+	name      string
+	children  []*treeNode
+	truncated bool
+	x         float64
+	level     int
+}
+
+// buildTree expands the best recipes of name down to the base elements.
+// Shared ingredients are drawn again wherever they are used.
+func (g *recipeGraph) buildTree(name string, level int, nodes *int) *treeNode {
+	*nodes++
+	node := &treeNode{name: name, level: level}
+	recipe, ok := g.best[name]
+	if !ok {
+		return node
+	}
+	if level >= maxTreeDepth || *nodes >= maxTreeNodes {
+		node.truncated = true
+		return node
+	}
+	node.children = []*treeNode{
+		g.buildTree(recipe.First, level+1, nodes),
+		g.buildTree(recipe.Second, level+1, nodes),
+	}
+	return node
+}
+
+// layoutTree places leaves in consecutive slots and parents centered over
+// their children, returning the number of slots and levels used.
+func layoutTree(node *treeNode, nextSlot *int) (levels int) {
+	if len(node.children) == 0 {
+		node.x = float64(*nextSlot)
+		*nextSlot++
+		return node.level + 1
+	}
+	for _, child := range node.children {
+		levels = max(levels, layoutTree(child, nextSlot))
+	}
+	node.x = (node.children[0].x + node.children[len(node.children)-1].x) / 2
+	return levels
+}
+
+func handleTreeSVG(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if _, ok := graph.depth[name]; !ok {
+		http.Error(w, "No recipe found", http.StatusNotFound)
+		return
+	}
+
+	nodes := 0
+	root := graph.buildTree(name, 0, &nodes)
+	slots := 0
+	levels := layoutTree(root, &slots)
+
+	emoji, err := getEmojis(r.Context(), root)
+	if err != nil {
+		logrus.Errorf("Error fetching emoji: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	center := func(node *treeNode) (float64, float64) {
+		return node.x*treeNodeWidth + treeNodeWidth/2, float64(node.level*treeLevelHeight) + treeNodeHeight/2 + 10
+	}
+	var edges, boxes strings.Builder
+	var draw func(node *treeNode)
+	draw = func(node *treeNode) {
+		x, y := center(node)
+		for _, child := range node.children {
+			cx, cy := center(child)
+			fmt.Fprintf(&edges, `<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f"/>`+"\n", x, y+treeNodeHeight/2, cx, cy-treeNodeHeight/2)
+			draw(child)
+		}
+		label := emoji[node.name] + " " + node.name
+		if node.truncated {
+			label += " …"
+		}
+		fmt.Fprintf(&boxes, `<rect x="%.1f" y="%.1f" width="%d" height="%d" rx="8"/><text x="%.1f" y="%.1f">%s</text>`+"\n",
+			x-treeNodeWidth/2+5, y-treeNodeHeight/2, treeNodeWidth-10, treeNodeHeight, x, y, template.HTMLEscapeString(label))
+	}
+	draw(root)
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="sans-serif" font-size="13">
+<style>line{stroke:#9ca3af;stroke-width:1.5}rect{fill:#374151}text{fill:#fff;text-anchor:middle;dominant-baseline:middle}</style>
+<rect width="100%%" height="100%%" fill="#1f2937"/>
+%s%s</svg>
+`, slots*treeNodeWidth, levels*treeLevelHeight+20, edges.String(), boxes.String())
+}
+
+// getEmojis looks up the emoji of every item in a tree.
+func getEmojis(ctx context.Context, root *treeNode) (map[string]string, error) {
+	emoji := make(map[string]string)
+	var args []interface{}
+	var collect func(node *treeNode)
+	collect = func(node *treeNode) {
+		if _, ok := emoji[node.name]; !ok {
+			emoji[node.name] = ""
+			args = append(args, node.name)
+		}
+		for _, child := range node.children {
+			collect(child)
+		}
+	}
+	collect(root)
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(args)), ",")
+	rows, err := db.QueryContext(ctx, `SELECT name, emoji FROM items WHERE name IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, e string
+		if err := rows.Scan(&name, &e); err != nil {
+			return nil, err
+		}
+		emoji[name] = e
+	}
+	return emoji, rows.Err()
+}
+
+// handleComplexity serves the number of distinct items needed to craft an
+// item from the base elements.
+func handleComplexity(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	count, ok := graph.ancestorCount(name)
+	if !ok {
+		http.Error(w, "No recipe found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Item      string `json:"item"`
+		Ancestors int    `json:"ancestors"`
+	}{Item: name, Ancestors: count})
+}
+
+// handleRecipes serves up to n alternative build orders for an item, one for
+// each of its shallowest distinct top-level recipes.
+func handleRecipes(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	n, err := strconv.Atoi(r.URL.Query().Get("n"))
+	if err != nil || n <= 0 {
+		n = 3
+	}
+	if n > maxRecipePaths {
+		n = maxRecipePaths
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	paths := graph.recipePaths(name, n)
+	if len(paths) == 0 {
+		http.Error(w, "No recipe found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Item  string         `json:"item"`
+		Paths [][]recipeStep `json:"paths"`
+	}{Item: name, Paths: paths})
+}
+
+// handleAdjacency serves the direct neighbours of an item as pairs:
+// ingredientsOf holds [partner, result] for each combination the item is an
+// ingredient of, resultsFrom holds [first, second] for each recipe producing
+// it. ingredientsOf can be large and is paginated with offset and limit.
+func handleAdjacency(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	if offset < 0 {
+		offset = 0
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	item, err := getItem(r.Context(), name)
+	if err != nil {
+		logrus.Errorf("Error fetching item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if item == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	// Fetch one extra row to know whether there is a next page
+	ingredientsOf, err := queryPairs(`SELECT CASE WHEN firstItem = ? THEN secondItem ELSE firstItem END, resultItem
+FROM combinations
+WHERE firstItem = ? OR secondItem = ?
+ORDER BY id
+LIMIT ? OFFSET ?`, name, name, name, limit+1, offset)
+	if err != nil {
+		logrus.Errorf("Error fetching adjacency: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	var nextOffset *int
+	if len(ingredientsOf) > limit {
+		ingredientsOf = ingredientsOf[:limit]
+		next := offset + limit
+		nextOffset = &next
+	}
+
+	resultsFrom, err := queryPairs(`SELECT firstItem, secondItem FROM combinations WHERE resultItem = ? ORDER BY id`, name)
+	if err != nil {
+		logrus.Errorf("Error fetching adjacency: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Item          string      `json:"item"`
+		IngredientsOf [][2]string `json:"ingredientsOf"`
+		NextOffset    *int        `json:"nextOffset"`
+		ResultsFrom   [][2]string `json:"resultsFrom"`
+	}{Item: name, IngredientsOf: ingredientsOf, NextOffset: nextOffset, ResultsFrom: resultsFrom})
+}
+
+// handleEasiest serves the shallowest item whose name contains the given
+// substring, preferring shorter names. It relies on the depth column filled
+// by /admin/compute-metrics.
+func handleEasiest(w http.ResponseWriter, r *http.Request) {
+	if !requireMetrics(w) {
+		return
+	}
+	contains := strings.TrimSpace(r.URL.Query().Get("contains"))
+	if contains == "" {
+		http.Error(w, "Missing contains parameter", http.StatusBadRequest)
+		return
+	}
+	if len(contains) > maxQueryLength {
+		http.Error(w, fmt.Sprintf("Search query too long, at most %d bytes allowed", maxQueryLength), http.StatusBadRequest)
+		return
+	}
+
+	var item Item
+	var depth int
+	err := db.QueryRow(`SELECT name, emoji, isNew, depth FROM items
+WHERE name LIKE ? ESCAPE '\' AND depth IS NOT NULL
+ORDER BY depth, length(name), name
+LIMIT 1`, "%"+escapeLike(contains)+"%").Scan(&item.Name, &item.Emoji, &item.IsNew, &depth)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		logrus.Errorf("Error fetching easiest item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(struct {
+		Name  string `json:"name"`
+		Emoji string `json:"emoji"`
+		IsNew bool   `json:"isNew"`
+		Depth int    `json:"depth"`
+	}{Name: item.Name, Emoji: item.Emoji, IsNew: item.IsNew, Depth: depth})
+}
+
+// handleTier pages through the items at one depth, as stored in the depth
+// column by /admin/compute-metrics.
+func handleTier(w http.ResponseWriter, r *http.Request) {
+	if !requireMetrics(w) {
+		return
+	}
+	depth, err := strconv.Atoi(r.PathValue("n"))
+	if err != nil || depth < 0 {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	items, hasNext, err := tierItems(r.Context(), depth, page)
+	if err != nil {
+		logrus.Errorf("Error fetching tier items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if len(items) == 0 {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+	var deeper bool
+	if err := db.QueryRowContext(r.Context(), `SELECT EXISTS (SELECT 1 FROM items WHERE depth = ?)`, depth+1).Scan(&deeper); err != nil {
+		logrus.Errorf("Error fetching tier items: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// PrevTier is -1 at depth 0, templates can't tell 0 from unset otherwise
+	data := struct {
+		Depth    int
+		Items    []Item
+		Page     int
+		PrevPage int
+		NextPage int
+		PrevTier int
+		NextTier int
+	}{Depth: depth, Items: items, Page: page, PrevPage: page - 1, PrevTier: depth - 1}
+	if hasNext {
+		data.NextPage = page + 1
+	}
+	if deeper {
+		data.NextTier = depth + 1
+	}
+
+	content := &bytes.Buffer{}
+	if err := executeTemplate(content, "tier.html", data); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, fmt.Sprintf("Depth %d | Infinite Craft Search", depth), template.HTML(content.String()))
+}
+
+// tierItems returns one page of the items at depth ordered by name, and
+// whether there is a next page. The depth index narrows it to the tier.
+func tierItems(ctx context.Context, depth, page int) ([]Item, bool, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name, emoji, isNew FROM items WHERE depth = ? ORDER BY name LIMIT ? OFFSET ?`,
+		depth, browsePageSize+1, (page-1)*browsePageSize)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	items := make([]Item, 0, browsePageSize+1)
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.Name, &item.Emoji, &item.IsNew); err != nil {
+			return nil, false, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(items) > browsePageSize {
+		return items[:browsePageSize], true, nil
+	}
+	return items, false, nil
+}
+
+// handleTier1 lists the combinations of two base elements, as an HTML page or
+// as JSON with format=json.
+func handleTier1(w http.ResponseWriter, r *http.Request) {
+	placeholders, base := baseElementArgs()
+	rows, err := db.QueryContext(r.Context(), `SELECT
+	A.name AS firstName,
+	A.emoji AS firstEmoji,
+	B.name AS secondName,
+	B.emoji AS secondEmoji,
+	C.name AS resultName,
+	C.emoji AS resultEmoji
+`+combinationsWithIngredients+`
+JOIN
+	items C ON combinations.resultItem = C.name
+WHERE combinations.firstItem IN (`+placeholders+`)
+	AND combinations.secondItem IN (`+placeholders+`)
+ORDER BY combinations.id`, slices.Concat(base, base)...)
+	if err != nil {
+		logrus.Errorf("Error fetching tier 1 combinations: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	combinations := make([]Combination, 0)
+	for rows.Next() {
+		c := Combination{Item1: &Item{}, Item2: &Item{}, Result: &Item{}}
+		if err := rows.Scan(&c.Item1.Name, &c.Item1.Emoji, &c.Item2.Name, &c.Item2.Emoji, &c.Result.Name, &c.Result.Emoji); err != nil {
+			logrus.Errorf("Error fetching tier 1 combinations: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		combinations = append(combinations, c)
+	}
+
+	if r.URL.Query().Get("format") == "json" {
+		steps := make([]recipeStep, len(combinations))
+		for i, c := range combinations {
+			steps[i] = recipeStep{First: c.Item1.Name, Second: c.Item2.Name, Result: c.Result.Name}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(steps)
+		return
+	}
+
+	content := &bytes.Buffer{}
+	if err := executeTemplate(content, "tier1.html", combinations); err != nil {
+		logrus.Errorf("Error executing template: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	renderStartPage(w, "Tier 1 Recipes | Infinite Craft Search", template.HTML(content.String()))
+}
+
+// handleBaseProducing lists the combinations that produce a base element from
+// two ingredients that aren't base elements themselves.
+func handleBaseProducing(w http.ResponseWriter, r *http.Request) {
+	placeholders, base := baseElementArgs()
+	rows, err := db.QueryContext(r.Context(), `SELECT id, firstItem, secondItem, resultItem FROM combinations
+WHERE resultItem IN (`+placeholders+`)
+	AND firstItem NOT IN (`+placeholders+`)
+	AND secondItem NOT IN (`+placeholders+`)
+ORDER BY resultItem, id`, slices.Concat(base, base, base)...)
+	if err != nil {
+		logrus.Errorf("Error fetching base producing combinations: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	combinations := make([]CombinationRow, 0)
+	for rows.Next() {
+		var c CombinationRow
+		if err := rows.Scan(&c.ID, &c.First, &c.Second, &c.Result); err != nil {
+			logrus.Errorf("Error fetching base producing combinations: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		combinations = append(combinations, c)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(combinations)
+}
+
+const (
+	maxConvergent = 500
+	// maxConvergentPairs caps the pairs listed for a single result.
+	maxConvergentPairs = 100
+)
+
+// ConvergentResult is an item produced by several distinct ingredient pairs.
+// Pairs counts the distinct pairs, which may be more than are listed.
+type ConvergentResult struct {
+	Result  string       `json:"result"`
+	Pairs   int          `json:"pairs"`
+	Recipes []recipeStep `json:"recipes"`
+}
+
+// handleConvergent lists the results made by at least min distinct pairs,
+// ignoring ingredient order, with the pairs themselves.
+func handleConvergent(w http.ResponseWriter, r *http.Request) {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 || limit > maxConvergent {
+		limit = 50
+	}
+	minPairs, err := strconv.Atoi(r.URL.Query().Get("min"))
+	if err != nil || minPairs < 2 {
+		minPairs = 3
+	}
+
+	rows, err := db.QueryContext(r.Context(), `WITH convergent AS (
+	SELECT resultItem, COUNT(DISTINCT MIN(firstItem, secondItem) || char(0) || MAX(firstItem, secondItem)) AS pairs
+	FROM combinations
+	GROUP BY resultItem
+	HAVING pairs >= ?
+	ORDER BY pairs DESC, resultItem
+	LIMIT ?
+)
+SELECT c.resultItem, convergent.pairs, c.firstItem, c.secondItem
+FROM convergent
+JOIN combinations c ON c.resultItem = convergent.resultItem
+ORDER BY convergent.pairs DESC, c.resultItem, c.id`, minPairs, limit)
+	if err != nil {
+		logrus.Errorf("Error fetching convergent pairs: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	results := make([]ConvergentResult, 0)
+	seen := make(map[[2]string]bool)
+	for rows.Next() {
+		var step recipeStep
+		var pairs int
+		if err := rows.Scan(&step.Result, &pairs, &step.First, &step.Second); err != nil {
+			logrus.Errorf("Error fetching convergent pairs: %v", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		if len(results) == 0 || results[len(results)-1].Result != step.Result {
+			results = append(results, ConvergentResult{Result: step.Result, Pairs: pairs})
+			clear(seen)
+		}
+		current := &results[len(results)-1]
+
+		// A + B and B + A are the same pair
+		key := [2]string{min(step.First, step.Second), max(step.First, step.Second)}
+		if seen[key] || len(current.Recipes) >= maxConvergentPairs {
+			continue
+		}
+		seen[key] = true
+		current.Recipes = append(current.Recipes, step)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(results)
+}
+
+// queryPairs runs a query selecting two text columns.
+func queryPairs(query string, args ...interface{}) ([][2]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pairs := make([][2]string, 0)
+	for rows.Next() {
+		var p [2]string
+		if err := rows.Scan(&p[0], &p[1]); err != nil {
+			return nil, err
+		}
+		pairs = append(pairs, p)
+	}
+	return pairs, rows.Err()
+}
+
+func getItem(ctx context.Context, name string) (*Item, error) {
+	var item Item
+	stmt, err := db.PrepareContext(ctx, `SELECT name, emoji, isNew FROM items WHERE name = ?`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	row := stmt.QueryRowContext(ctx, name)
+	if err := row.Scan(&item.Name, &item.Emoji, &item.IsNew); err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+// combinationsWithIngredients joins the combinations to their ingredient
+// items as A and B.
+const combinationsWithIngredients = `FROM
+	combinations
+JOIN
+	items A ON combinations.firstItem = A.name
+JOIN
+	items B ON combinations.secondItem = B.name`
+
+func getCombinations(ctx context.Context, item *Item) ([]Combination, error) {
+	stmt, err := db.PrepareContext(ctx, `SELECT
+	A.name AS firstName,
+	A.emoji AS firstEmoji,
+	B.name AS secondName,
+	B.emoji AS secondEmoji
+`+combinationsWithIngredients+`
+WHERE
+	combinations.resultItem = ?`)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, item.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	combinations := make([]Combination, 0)
+
+	for rows.Next() {
+		combination := Combination{
+			Item1:  &Item{},
+			Item2:  &Item{},
+			Result: item,
+		}
+		if err := rows.Scan(&combination.Item1.Name, &combination.Item1.Emoji, &combination.Item2.Name, &combination.Item2.Emoji); err != nil {
+			return nil, err
+		}
+		logrus.Debugf("Combination: %v", combination)
+		combinations = append(combinations, combination)
+	}
+
+	return combinations, nil
+}
+
+// randomCombinationSample is how many random combinations are drawn to pick
+// the most interesting one from.
+const randomCombinationSample = 16
+
+// getRandomCombination picks a random combination, biased towards first
+// discoveries and deep results by picking the best of a small sample.
+func getRandomCombination(ctx context.Context) (*Combination, error) {
+	// Loaded first, it queries the database too and the pool may not have a
+	// second connection while rows holds one
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT
+	A.name AS firstName,
+	A.emoji AS firstEmoji,
+	B.name AS secondName,
+	B.emoji AS secondEmoji,
+	R.name AS resultName,
+	R.emoji AS resultEmoji,
+	R.isNew AS resultIsNew
+`+combinationsWithIngredients+`
+JOIN
+	items R ON combinations.resultItem = R.name
+WHERE
+	combinations.id IN (SELECT id FROM combinations ORDER BY RANDOM() LIMIT ?)`, randomCombinationSample)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var best *Combination
+	bestScore := -1
+	for rows.Next() {
+		combination := Combination{Item1: &Item{}, Item2: &Item{}, Result: &Item{}}
+		if err := rows.Scan(&combination.Item1.Name, &combination.Item1.Emoji, &combination.Item2.Name, &combination.Item2.Emoji,
+			&combination.Result.Name, &combination.Result.Emoji, &combination.Result.IsNew); err != nil {
+			return nil, err
+		}
+		score := graph.depth[combination.Result.Name]
+		if combination.Result.IsNew {
+			score += 10
+		}
+		if score > bestScore {
+			best, bestScore = &combination, score
+		}
+	}
+	return best, rows.Err()
+}
+
+// getCombinationsPage returns up to limit combinations with an id greater than
+// cursor. The returned cursor is nil once there are no further rows.
+func getCombinationsPage(cursor int64, limit int) ([]CombinationRow, *int64, error) {
+	rows, err := db.Query(`SELECT id, firstItem, secondItem, resultItem FROM combinations WHERE id > ? ORDER BY id LIMIT ?`, cursor, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	combinations := make([]CombinationRow, 0, limit)
+	for rows.Next() {
+		var c CombinationRow
+		if err := rows.Scan(&c.ID, &c.First, &c.Second, &c.Result); err != nil {
+			return nil, nil, err
+		}
+		combinations = append(combinations, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	if len(combinations) < limit {
+		return combinations, nil, nil
+	}
+	nextCursor := combinations[len(combinations)-1].ID
+	return combinations, &nextCursor, nil
+}
+
+const (
+	relatedItemsLimit     = 8
+	relatedItemsCacheSize = 1000
+)
+
+var (
+	relatedItemsCache        = make(map[string][]RelatedItem)
+	relatedItemsCacheVersion int64
+	relatedItemsCacheMu      sync.Mutex
+)
+
+// getRelatedItems finds the items whose recipes share the most ingredients
+// with the recipes of the given item. Results are cached, the whole cache is
+// dropped when the data version changes or it grows past
+// relatedItemsCacheSize.
+func getRelatedItems(name string) ([]RelatedItem, error) {
+	version, err := getDataVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	relatedItemsCacheMu.Lock()
+	if version != relatedItemsCacheVersion {
+		relatedItemsCache = make(map[string][]RelatedItem)
+		relatedItemsCacheVersion = version
+	}
+	related, ok := relatedItemsCache[name]
+	relatedItemsCacheMu.Unlock()
+	if ok {
+		return related, nil
+	}
+
+	rows, err := db.Query(`WITH ingredients AS (
+	SELECT firstItem AS name FROM combinations WHERE resultItem = ?
+	UNION
+	SELECT secondItem FROM combinations WHERE resultItem = ?
+), uses AS (
+	SELECT resultItem, firstItem AS ingredient FROM combinations WHERE firstItem IN ingredients
+	UNION
+	SELECT resultItem, secondItem FROM combinations WHERE secondItem IN ingredients
+)
+SELECT items.name, items.emoji, COUNT(*) AS shared
+FROM uses
+JOIN items ON items.name = uses.resultItem
+WHERE uses.resultItem != ?
+GROUP BY uses.resultItem
+ORDER BY shared DESC, items.name
+LIMIT ?`, name, name, name, relatedItemsLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	related = make([]RelatedItem, 0, relatedItemsLimit)
+	for rows.Next() {
+		var r RelatedItem
+		if err := rows.Scan(&r.Name, &r.Emoji, &r.SharedIngredients); err != nil {
+			return nil, err
+		}
+		related = append(related, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	relatedItemsCacheMu.Lock()
+	if version == relatedItemsCacheVersion {
+		if len(relatedItemsCache) >= relatedItemsCacheSize {
+			relatedItemsCache = make(map[string][]RelatedItem)
+		}
+		relatedItemsCache[name] = related
+	}
+	relatedItemsCacheMu.Unlock()
+
+	return related, nil
+}
+
+// hasAliases is set when the database has an aliases table, which only
+// exists once mergeWhitespace.go has been run.
+var hasAliases bool
+
+// hasCreatedAt is set when combinations have the createdAt column, which the
+// collector adds on its first run.
+var hasCreatedAt bool
+
+// hasDepth is set once items have the depth and buildCost columns, which
+// /admin/compute-metrics adds while the server runs.
+var hasDepth atomic.Bool
+
+// requireMetrics answers 503 and returns false until /admin/compute-metrics
+// has stored the metric columns.
+func requireMetrics(w http.ResponseWriter) bool {
+	if hasDepth.Load() {
+		return true
+	}
+	http.Error(w, "Item metrics haven't been computed yet, run /admin/compute-metrics", http.StatusServiceUnavailable)
+	return false
+}
+
+func initDB(dataSourceName string) {
+	var err error
+	db, err = sql.Open("sqlite3", dataSourceName)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	db.SetMaxOpenConns(dbMaxOpen)
+	db.SetMaxIdleConns(dbMaxIdle)
+	db.SetConnMaxLifetime(dbConnMaxLifetime)
+	if err = db.Ping(); err != nil {
+		logrus.Fatal(err)
+	}
+	if err = checkSchema(); err != nil {
+		logrus.Fatalf("%s doesn't look like a database written by the collector: %v", dataSourceName, err)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'aliases'`).Scan(&hasAliases)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	err = db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('combinations') WHERE name = 'createdAt'`).Scan(&hasCreatedAt)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	var depth bool
+	err = db.QueryRow(`SELECT COUNT(*) > 0 FROM pragma_table_info('items') WHERE name = 'depth'`).Scan(&depth)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+	hasDepth.Store(depth)
+	// Lookups by ingredient need both columns indexed, the UNIQUE constraint
+	// only covers firstItem. Recipe counts look up by result, and searches by
+	// result walk it in order.
+	if !readOnly {
+		for _, column := range []string{"secondItem", "resultItem"} {
+			_, err = db.Exec(`CREATE INDEX IF NOT EXISTS idx_combinations_` + column + ` ON combinations(` + column + `)`)
+			if err != nil {
+				logrus.Fatal(err)
+			}
+		}
+	}
+}
+
+// requiredColumns are the columns every query relies on. Columns added later
+// by migrations are checked where they are used.
+var requiredColumns = map[string][]string{
+	"items":        {"name", "emoji", "isNew"},
+	"combinations": {"id", "firstItem", "secondItem", "resultItem"},
+}
+
+// checkSchema reports the first table or column from requiredColumns that is
+// missing.
+func checkSchema() error {
+	for table, columns := range requiredColumns {
+		present := make(map[string]bool)
+		rows, err := db.Query(`SELECT name FROM pragma_table_info(?)`, table)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var column string
+			if err := rows.Scan(&column); err != nil {
+				rows.Close()
+				return err
+			}
+			present[column] = true
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(present) == 0 {
+			return fmt.Errorf("missing table %s", table)
+		}
+		for _, column := range columns {
+			if !present[column] {
+				return fmt.Errorf("table %s has no column %s", table, column)
+			}
+		}
+	}
+	return nil
+}
+
+// getCanonicalName returns the item an alias was merged into, or "" if name
+// isn't an alias.
+func getCanonicalName(name string) (string, error) {
+	if !hasAliases {
+		return "", nil
+	}
+	var canonical string
+	err := db.QueryRow(`SELECT canonical FROM aliases WHERE alias = ?`, name).Scan(&canonical)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return canonical, err
+}
+
+type Item struct {
+	Name  string
+	Emoji string
+	IsNew bool
+}
+
+type Combination struct {
+	Item1  *Item
+	Item2  *Item
+	Result *Item
+}
+
+type RelatedItem struct {
+	Name              string
+	Emoji             string
+	SharedIngredients int
+}
+
+type Hub struct {
+	Name      string
+	Emoji     string
+	InDegree  int // recipes producing the item
+	OutDegree int // combinations using the item
+}
+
+func (h Hub) Degree() int {
+	return h.InDegree + h.OutDegree
+}
+
+type RecipeCount struct {
+	Name    string
+	Emoji   string
+	Recipes int
+}
+
+type EmojiCount struct {
+	Emoji string `json:"emoji"`
+	Items int    `json:"items"`
+}
+
+// CombinationRow is a raw row of the combinations table as served by the API.
+type CombinationRow struct {
+	ID     int64  `json:"id"`
+	First  string `json:"firstItem"`
+	Second string `json:"secondItem"`
+	Result string `json:"resultItem"`
+}
+
+// searchOptions are the filters of a search, zero values don't filter.
+type searchOptions struct {
+	Query    string
+	Category string // as assigned by the tagCategories command
+	MinLen   int    // shortest name length
+	MaxLen   int    // longest name length
+	// Sort orders the results by a stored metric, see searchSortColumns.
+	Sort string
+}
+
+// searchSortColumns maps the accepted sort options to their ORDER BY clause.
+// The metric columns are filled by /admin/compute-metrics.
+var searchSortColumns = map[string]string{
+	"depth":     `depth IS NULL, depth, name`,
+	"buildCost": `buildCost IS NULL, buildCost, name`,
+}
+
+func (o searchOptions) unfiltered() bool {
+	return o.Query == "" && o.Category == "" && o.MinLen == 0 && o.MaxLen == 0
+}
+
+// searchItems matches items by name substring, optionally restricted to a
+// category and a name length range.
+func searchItems(ctx context.Context, opts searchOptions) ([]Item, bool, error) {
+
+	limit := searchLimit
+	var items []Item
+	// matchRank puts an exact (case-insensitive) match first, then prefix
+	// matches, then all other substring matches
+	sqlQuery := `SELECT name, emoji, isNew,
+	CASE WHEN name = ? COLLATE NOCASE THEN 0 WHEN name LIKE ? ESCAPE '\' THEN 1 ELSE 2 END AS matchRank
+FROM items WHERE name LIKE ? ESCAPE '\'`
+	escaped := escapeLike(opts.Query)
+	args := []interface{}{opts.Query, escaped + "%", "%" + escaped + "%"}
+	if opts.Category != "" {
+		sqlQuery += ` AND category = ?`
+		args = append(args, opts.Category)
+	}
+	if opts.MinLen > 0 || opts.MaxLen > 0 {
+		maxLen := opts.MaxLen
+		if maxLen == 0 {
+			maxLen = math.MaxInt32
+		}
+		sqlQuery += ` AND length(name) BETWEEN ? AND ?`
+		args = append(args, opts.MinLen, maxLen)
+	}
+	if orderBy, ok := searchSortColumns[opts.Sort]; ok {
+		sqlQuery += ` ORDER BY ` + orderBy
+	} else {
+		sqlQuery += ` ORDER BY matchRank, name`
+	}
+	sqlQuery += ` LIMIT ?`
+	args = append(args, limit)
+
+	stmt, err := db.PrepareContext(ctx, sqlQuery)
+	if err != nil {
+		return nil, false, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item Item
+		var matchRank int
+		if err := rows.Scan(&item.Name, &item.Emoji, &item.IsNew, &matchRank); err != nil {
+			return nil, false, err
+		}
+		items = append(items, item)
+	}
+
+	return items, len(items) == limit, nil
+}
+
+// browseItems returns one page of items starting with letter, and whether
+// there is a next page.
+func browseItems(letter string, page int) ([]Item, bool, error) {
+	condition := `upper(substr(name, 1, 1)) = ?`
+	args := []interface{}{letter}
+	if letter == "#" {
+		condition = `upper(substr(name, 1, 1)) NOT BETWEEN 'A' AND 'Z'`
+		args = nil
+	}
+	args = append(args, browsePageSize+1, (page-1)*browsePageSize)
+
+	rows, err := db.Query(`SELECT name, emoji, isNew FROM items WHERE `+condition+` ORDER BY name COLLATE NOCASE LIMIT ? OFFSET ?`, args...)
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	items := make([]Item, 0, browsePageSize+1)
+	for rows.Next() {
+		var item Item
+		if err := rows.Scan(&item.Name, &item.Emoji, &item.IsNew); err != nil {
+			return nil, false, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+
+	if len(items) > browsePageSize {
+		return items[:browsePageSize], true, nil
+	}
+	return items, false, nil
+}
+
+// getHubs ranks the top maxHubs items by their total degree.
+func getHubs() ([]Hub, error) {
+	rows, err := db.Query(`SELECT items.name, items.emoji, COALESCE(produced.n, 0) AS inDegree, COALESCE(used.n, 0) AS outDegree
+FROM items
+LEFT JOIN (SELECT resultItem AS name, COUNT(*) AS n FROM combinations GROUP BY resultItem) produced ON produced.name = items.name
+LEFT JOIN (
+	SELECT name, COUNT(*) AS n FROM (
+		SELECT id, firstItem AS name FROM combinations
+		UNION
+		SELECT id, secondItem FROM combinations
+	) GROUP BY name
+) used ON used.name = items.name
+ORDER BY inDegree + outDegree DESC, items.name
+LIMIT ?`, maxHubs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hubs := make([]Hub, 0, maxHubs)
+	for rows.Next() {
+		var hub Hub
+		if err := rows.Scan(&hub.Name, &hub.Emoji, &hub.InDegree, &hub.OutDegree); err != nil {
+			return nil, err
+		}
+		hubs = append(hubs, hub)
+	}
+	return hubs, rows.Err()
+}
+
+// getMostRecipes returns the top maxRecipeCounts items by recipe count.
+func getMostRecipes() ([]RecipeCount, error) {
+	rows, err := db.Query(`SELECT items.name, items.emoji, COUNT(*) AS recipes
+FROM combinations
+JOIN items ON items.name = combinations.resultItem
+GROUP BY combinations.resultItem
+ORDER BY COUNT(*) DESC, items.name
+LIMIT ?`, maxRecipeCounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]RecipeCount, 0, maxRecipeCounts)
+	for rows.Next() {
+		var c RecipeCount
+		if err := rows.Scan(&c.Name, &c.Emoji, &c.Recipes); err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// versionCache holds a computed value until the data version changes, i.e.
+// until a crawl wrote to the database.
+type versionCache[T any] struct {
+	mu      sync.Mutex
+	version int64
+	valid   bool
+	value   T
+}
+
+func (c *versionCache[T]) get(load func() (T, error)) (T, error) {
+	version, err := getDataVersion()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.valid && c.version == version {
+		return c.value, nil
+	}
+	value, err := load()
+	if err != nil {
+		return value, err
+	}
+	c.value, c.version, c.valid = value, version, true
+	return value, nil
+}
+
+// pageCache keeps the most recently used rendered item pages. Like
+// versionCache, it is emptied whenever the data version changes: a new
+// combination can show up on the page of any item it uses, so there is no
+// cheaper per-item invalidation. renderItem also checks the item itself on
+// every hit, emoji-only changes to other items on a page wait for the next
+// combination.
+type pageCache struct {
+	mu      sync.Mutex
+	size    int
+	version int64
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type pageCacheEntry struct {
+	name string
+	item *Item
+	html template.HTML
+}
+
+var itemPages *pageCache
+
+// newPageCache returns a cache holding up to size pages, a size of 0 or less
+// disables it.
+func newPageCache(size int) *pageCache {
+	return &pageCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *pageCache) get(name string, version int64) (*Item, template.HTML, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if version != c.version {
+		return nil, "", false
+	}
+	elem, ok := c.entries[name]
+	if !ok {
+		return nil, "", false
+	}
+	c.order.MoveToFront(elem)
+	entry := elem.Value.(*pageCacheEntry)
+	return entry.item, entry.html, true
+}
+
+func (c *pageCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	clear(c.entries)
+}
+
+func (c *pageCache) add(name string, version int64, item *Item, html template.HTML) {
+	if c.size <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if version != c.version {
+		c.order.Init()
+		clear(c.entries)
+		c.version = version
+	}
+	if elem, ok := c.entries[name]; ok {
+		elem.Value = &pageCacheEntry{name, item, html}
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.entries[name] = c.order.PushFront(&pageCacheEntry{name, item, html})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*pageCacheEntry).name)
+	}
+}
+
+const (
+	maxSuggestions = 8
+	// maxEditDistance bounds how far a suggestion may be from the name.
+	maxEditDistance = 3
+)
+
+var itemNamesCache versionCache[[]string]
+
+func getItemNames() ([]string, error) {
+	rows, err := db.Query(`SELECT name FROM items`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// fuzzyMatches returns up to limit item names within maxEditDistance of name,
+// closest first. The comparison ignores case.
+func fuzzyMatches(name string, limit int) ([]string, error) {
+	names, err := itemNamesCache.get(getItemNames)
+	if err != nil {
+		return nil, err
+	}
+
+	type match struct {
+		name     string
+		distance int
+	}
+	target := []rune(strings.ToLower(name))
+	var matches []match
+	for _, candidate := range names {
+		runes := []rune(strings.ToLower(candidate))
+		// The distance is at least the difference in length
+		if diff := len(runes) - len(target); diff > maxEditDistance || -diff > maxEditDistance {
+			continue
+		}
+		if d := levenshtein(target, runes); d <= maxEditDistance {
+			matches = append(matches, match{candidate, d})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].distance != matches[j].distance {
+			return matches[i].distance < matches[j].distance
+		}
+		return matches[i].name < matches[j].name
+	})
+
+	result := make([]string, 0, limit)
+	for i := 0; i < len(matches) && i < limit; i++ {
+		result = append(result, matches[i].name)
+	}
+	return result, nil
+}
+
+// levenshtein is the edit distance between a and b.
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// escapeLike escapes the LIKE wildcards in s, for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return likeEscaper.Replace(s)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func getTotalItemCount() (int, error) {
+	var count int
+	row := db.QueryRow(`SELECT COUNT(*) FROM items`)
+	err := row.Scan(&count)
+	return count, err
+}
+
+func getTotalCombinationCount() (int, error) {
+	var count int
+	row := db.QueryRow(`SELECT COUNT(*) FROM combinations`)
+	err := row.Scan(&count)
+	return count, err
+}
+
+// getDataVersion returns the id of the newest combination, which changes
+// whenever one is recorded. Unlike the count it is a single lookup on the
+// primary key. Only deleting combinations, like the cleanup tools do, leaves
+// it unchanged, restart the server after running them.
+func getDataVersion() (int64, error) {
+	var version int64
+	row := db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM combinations`)
+	err := row.Scan(&version)
+	return version, err
+}
+
+// baseElements are the items every recipe tree starts from.
+var baseElements = []string{"Water", "Fire", "Wind", "Earth"}
+
+// baseElementArgs returns a placeholder list and matching arguments for
+// using the base elements in an IN clause.
+func baseElementArgs() (string, []interface{}) {
+	args := make([]interface{}, len(baseElements))
+	for i, base := range baseElements {
+		args[i] = base
+	}
+	return strings.TrimSuffix(strings.Repeat("?, ", len(baseElements)), ", "), args
+}
+
+func isBaseElement(name string) bool {
+	for _, base := range baseElements {
+		if name == base {
+			return true
+		}
+	}
+	return false
+}
+
+const maxRecipePaths = 10
+
+type recipeStep struct {
+	First  string `json:"first"`
+	Second string `json:"second"`
+	Result string `json:"result"`
+}
+
+// recipeGraph is an in-memory copy of the combinations table together with
+// the depth of every item reachable from the base elements. Base elements
+// have depth 0, any other item is one deeper than the deeper ingredient of
+// its shallowest recipe.
+type recipeGraph struct {
+	recipes map[string][]recipeStep // recipes by result, in id order
+	depth   map[string]int
+	best    map[string]recipeStep // shallowest recipe of each reachable item
+	version int64                 // data version the graph was built at
+
+	complexityMu sync.Mutex
+	complexity   map[string]int // memoized results of ancestorCount
+
+	selfDependentOnce  sync.Once
+	selfDependentItems []SelfDependentItem
+}
+
+var (
+	cachedGraph   *recipeGraph
+	cachedGraphMu sync.Mutex
+)
+
+// loadRecipeGraph returns the cached recipe graph, rebuilding it whenever the
+// data version changed since it was built.
+func loadRecipeGraph() (*recipeGraph, error) {
+	version, err := getDataVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	cachedGraphMu.Lock()
+	defer cachedGraphMu.Unlock()
+	if cachedGraph != nil && cachedGraph.version == version {
+		return cachedGraph, nil
+	}
+
+	graph, err := buildRecipeGraph()
+	if err != nil {
+		return nil, err
+	}
+	graph.version = version
+	cachedGraph = graph
+	return graph, nil
+}
+
+func buildRecipeGraph() (*recipeGraph, error) {
+	rows, err := db.Query(`SELECT firstItem, secondItem, resultItem FROM combinations ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	graph := &recipeGraph{recipes: make(map[string][]recipeStep)}
+	for rows.Next() {
+		var step recipeStep
+		if err := rows.Scan(&step.First, &step.Second, &step.Result); err != nil {
+			return nil, err
+		}
+		graph.recipes[step.Result] = append(graph.recipes[step.Result], step)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	graph.computeDepths()
+	return graph, nil
+}
+
+// computeDepths relaxes the depths until they no longer change. Items that
+// can't be crafted from the base elements get no depth at all.
+func (g *recipeGraph) computeDepths() {
+	g.computeDepthsFrom(baseElements)
+}
+
+// computeDepthsFrom computes the depths with sources as the free items at
+// depth 0, the base elements unless planning for an inventory.
+func (g *recipeGraph) computeDepthsFrom(sources []string) {
+	g.depth = make(map[string]int)
+	g.best = make(map[string]recipeStep)
+	isSource := make(map[string]bool)
+	for _, source := range sources {
+		g.depth[source] = 0
+		isSource[source] = true
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for result, recipes := range g.recipes {
+			if isSource[result] {
+				continue
+			}
+			for _, recipe := range recipes {
+				d, ok := g.recipeDepth(recipe)
+				if !ok {
+					continue
+				}
+				if current, known := g.depth[result]; !known || d < current {
+					g.depth[result] = d
+					changed = true
+				}
+			}
+		}
+	}
+
+	// Pick the first shallowest recipe so the chosen build orders are stable
+	for result, recipes := range g.recipes {
+		if isSource[result] {
+			continue
+		}
+		for _, recipe := range recipes {
+			if d, ok := g.recipeDepth(recipe); ok && d == g.depth[result] {
+				g.best[result] = recipe
+				break
+			}
+		}
+	}
+}
+
+// recipeDepth is the depth a recipe's result has when crafted with it.
+func (g *recipeGraph) recipeDepth(recipe recipeStep) (int, bool) {
+	first, ok := g.depth[recipe.First]
+	if !ok {
+		return 0, false
+	}
+	second, ok := g.depth[recipe.Second]
+	if !ok {
+		return 0, false
+	}
+	return max(first, second) + 1, true
+}
+
+// hasRecipe reports whether the dataset records step, in either order.
+func (g *recipeGraph) hasRecipe(step recipeStep) bool {
+	for _, recipe := range g.recipes[step.Result] {
+		if recipe.First == step.First && recipe.Second == step.Second ||
+			recipe.First == step.Second && recipe.Second == step.First {
+			return true
+		}
+	}
+	return false
+}
+
+// shortestRecipe returns the steps to craft an item from the base elements,
+// ingredients before the items they are used in. Base elements need no steps,
+// ok is false when the item can't be reached at all.
+func (g *recipeGraph) shortestRecipe(name string) (steps []recipeStep, ok bool) {
+	if _, ok := g.depth[name]; !ok {
+		return nil, false
+	}
+	steps = make([]recipeStep, 0)
+	g.appendSteps(name, make(map[string]bool), &steps)
+	return steps, true
+}
+
+// appendSteps adds the build order of an item to steps, skipping items that
+// are already crafted. Following the best recipes always goes to a strictly
+// lower depth, so this can't loop.
+func (g *recipeGraph) appendSteps(name string, crafted map[string]bool, steps *[]recipeStep) {
+	// Only the sources have no best recipe
+	recipe, ok := g.best[name]
+	if crafted[name] || !ok {
+		return
+	}
+	g.appendSteps(recipe.First, crafted, steps)
+	g.appendSteps(recipe.Second, crafted, steps)
+	crafted[name] = true
+	*steps = append(*steps, recipe)
+}
+
+// recipePaths returns up to n distinct build orders for an item, trying its
+// recipes from shallowest to deepest as the final step.
+func (g *recipeGraph) recipePaths(name string, n int) [][]recipeStep {
+	if isBaseElement(name) {
+		return [][]recipeStep{{}}
+	}
+
+	type candidate struct {
+		recipe recipeStep
+		depth  int
+	}
+	var candidates []candidate
+	for _, recipe := range g.recipes[name] {
+		if d, ok := g.recipeDepth(recipe); ok {
+			candidates = append(candidates, candidate{recipe, d})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].depth < candidates[j].depth
+	})
+
+	paths := make([][]recipeStep, 0, n)
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		if len(paths) == n {
+			break
+		}
+		steps := make([]recipeStep, 0)
+		crafted := make(map[string]bool)
+		g.appendSteps(c.recipe.First, crafted, &steps)
+		g.appendSteps(c.recipe.Second, crafted, &steps)
+		// A deeper recipe can need the item itself as an intermediate
+		if crafted[name] {
+			continue
+		}
+		steps = append(steps, c.recipe)
+
+		key := recipeKey(steps)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		paths = append(paths, steps)
+	}
+	return paths
+}
+
+// ancestorCount is the number of distinct items, base elements included, in
+// the recipe tree of an item built from shallowest recipes. Counts are
+// memoized on the graph, which is rebuilt whenever the data changes.
+func (g *recipeGraph) ancestorCount(name string) (int, bool) {
+	g.complexityMu.Lock()
+	defer g.complexityMu.Unlock()
+	if count, ok := g.complexity[name]; ok {
+		return count, true
+	}
+
+	steps, ok := g.shortestRecipe(name)
+	if !ok {
+		return 0, false
+	}
+	ancestors := make(map[string]bool)
+	for _, step := range steps {
+		ancestors[step.First] = true
+		ancestors[step.Second] = true
+		ancestors[step.Result] = true
+	}
+	delete(ancestors, name)
+
+	if g.complexity == nil {
+		g.complexity = make(map[string]int)
+	}
+	g.complexity[name] = len(ancestors)
+	return len(ancestors), true
+}
+
+// plan returns the steps to craft target when the owned items are free like
+// the base elements, ok is false when it can't be reached from them.
+func (g *recipeGraph) plan(target string, owned []string) ([]recipeStep, bool) {
+	planner := &recipeGraph{recipes: g.recipes}
+	planner.computeDepthsFrom(append(slices.Clone(baseElements), owned...))
+	return planner.shortestRecipe(target)
+}
+
+// nextGoals returns the items that are neither owned nor base elements and
+// take at most maxDepth rounds of crafting from the owned items, fewest
+// combinations first.
+func (g *recipeGraph) nextGoals(owned []string, maxDepth int) []Goal {
+	planner := &recipeGraph{recipes: g.recipes}
+	planner.computeDepthsFrom(append(slices.Clone(baseElements), owned...))
+
+	goals := make([]Goal, 0)
+	for name, d := range planner.depth {
+		if d == 0 || d > maxDepth {
+			continue
+		}
+		steps, _ := planner.shortestRecipe(name)
+		goals = append(goals, Goal{Name: name, Steps: steps})
+	}
+	sort.Slice(goals, func(i, j int) bool {
+		if len(goals[i].Steps) != len(goals[j].Steps) {
+			return len(goals[i].Steps) < len(goals[j].Steps)
+		}
+		return goals[i].Name < goals[j].Name
+	})
+	return goals
+}
+
+// selfDependent returns the unreachable items that become craftable once the
+// item itself is owned, ordered by name. Items also blocked by other
+// unreachable items are plain islands and not included. The result is
+// computed once per graph.
+//
+// Whatever owning an item makes craftable needs the item, so the ingredients
+// of its cycle are all in its component of unreachableComponents. Each
+// candidate is only planned for within its component.
+func (g *recipeGraph) selfDependent() []SelfDependentItem {
+	g.selfDependentOnce.Do(func() {
+		g.selfDependentItems = make([]SelfDependentItem, 0)
+		for _, component := range g.unreachableComponents() {
+			inComponent := make(map[string]bool)
+			for _, name := range component {
+				inComponent[name] = true
+			}
+			// The recipes made only of craftable items and the component,
+			// with the craftable ones as free sources
+			sub := &recipeGraph{recipes: make(map[string][]recipeStep)}
+			var craftable []string
+			for _, name := range component {
+				for _, recipe := range g.recipes[name] {
+					usable := true
+					for _, ingredient := range []string{recipe.First, recipe.Second} {
+						if _, ok := g.depth[ingredient]; ok {
+							craftable = append(craftable, ingredient)
+						} else if !inComponent[ingredient] {
+							usable = false
+						}
+					}
+					if usable {
+						sub.recipes[name] = append(sub.recipes[name], recipe)
+					}
+				}
+			}
+
+			for _, name := range component {
+				sub.computeDepthsFrom(append(slices.Clone(craftable), name))
+				for _, recipe := range sub.recipes[name] {
+					if _, ok := sub.recipeDepth(recipe); !ok {
+						continue
+					}
+					cycle := make([]recipeStep, 0)
+					crafted := make(map[string]bool)
+					sub.appendSteps(recipe.First, crafted, &cycle)
+					sub.appendSteps(recipe.Second, crafted, &cycle)
+					cycle = append(cycle, recipe)
+					g.selfDependentItems = append(g.selfDependentItems, SelfDependentItem{Name: name, Cycle: cycle})
+					break
+				}
+			}
+		}
+		sort.Slice(g.selfDependentItems, func(i, j int) bool {
+			return g.selfDependentItems[i].Name < g.selfDependentItems[j].Name
+		})
+	})
+	return g.selfDependentItems
+}
+
+// unreachableComponents returns the strongly connected components of the
+// unreachable items, linked to the unreachable ingredients of their recipes.
+// Only components in a cycle are returned, so an item needs itself exactly
+// when it is in one. It is Tarjan's algorithm, a single pass over the
+// recipes of the unreachable items.
+func (g *recipeGraph) unreachableComponents() [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(name string)
+	visit = func(name string) {
+		index[name] = len(index)
+		low[name] = index[name]
+		stack = append(stack, name)
+		onStack[name] = true
+
+		selfLoop := false
+		for _, recipe := range g.recipes[name] {
+			for _, ingredient := range []string{recipe.First, recipe.Second} {
+				if _, ok := g.depth[ingredient]; ok {
+					continue
+				}
+				if ingredient == name {
+					selfLoop = true
+				}
+				if _, visited := index[ingredient]; !visited {
+					visit(ingredient)
+					low[name] = min(low[name], low[ingredient])
+				} else if onStack[ingredient] {
+					low[name] = min(low[name], index[ingredient])
+				}
+			}
+		}
+
+		if low[name] != index[name] {
+			return
+		}
+		i := len(stack) - 1
+		for stack[i] != name {
+			i--
+		}
+		component := slices.Clone(stack[i:])
+		stack = stack[:i]
+		for _, member := range component {
+			onStack[member] = false
+		}
+		if len(component) > 1 || selfLoop {
+			components = append(components, component)
+		}
+	}
+
+	for name := range g.recipes {
+		if _, ok := g.depth[name]; ok {
+			continue
+		}
+		if _, visited := index[name]; !visited {
+			visit(name)
+		}
+	}
+	return components
+}
+
+// BlockedRecipe is a recipe of an unreachable item with the ingredients that
+// are unreachable themselves.
+type BlockedRecipe struct {
+	First       string   `json:"first"`
+	Second      string   `json:"second"`
+	Unreachable []string `json:"unreachable"`
+}
+
+// unreachableCauses follows the unreachable ingredients of name's recipes as
+// far as they go. It returns the items that are never made at all, and the
+// items on the way that are only made from themselves, alone or as an island
+// of items making each other, both ordered by name. Each item is visited
+// once, which also cuts the cycles.
+func (g *recipeGraph) unreachableCauses(name string) (neverMade, selfDependent []string) {
+	cyclic := make(map[string]bool)
+	for _, component := range g.unreachableComponents() {
+		for _, item := range component {
+			cyclic[item] = true
+		}
+	}
+
+	neverMade, selfDependent = make([]string, 0), make([]string, 0)
+	seen := map[string]bool{name: true}
+	stack := []string{name}
+	for len(stack) > 0 {
+		item := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if len(g.recipes[item]) == 0 {
+			neverMade = append(neverMade, item)
+		}
+		if cyclic[item] {
+			selfDependent = append(selfDependent, item)
+		}
+		for _, recipe := range g.recipes[item] {
+			for _, ingredient := range []string{recipe.First, recipe.Second} {
+				if _, ok := g.depth[ingredient]; ok || seen[ingredient] {
+					continue
+				}
+				seen[ingredient] = true
+				stack = append(stack, ingredient)
+			}
+		}
+	}
+	sort.Strings(neverMade)
+	sort.Strings(selfDependent)
+	return neverMade, selfDependent
+}
+
+// handleWhyUnreachable explains why an item can't be crafted from the base
+// elements: which ingredients of its recipes are missing, and which items at
+// the root of it are never made or only made from themselves.
+func handleWhyUnreachable(w http.ResponseWriter, r *http.Request) {
+	item, err := getItem(r.Context(), r.PathValue("name"))
+	if err != nil {
+		logrus.Errorf("Error fetching item: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if item == nil {
+		http.Error(w, "Not Found", http.StatusNotFound)
+		return
+	}
+
+	graph, err := loadRecipeGraph()
+	if err != nil {
+		logrus.Errorf("Error loading recipe graph: %v", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if _, ok := graph.depth[item.Name]; ok {
+		json.NewEncoder(w).Encode(struct {
+			Item      string `json:"item"`
+			Reachable bool   `json:"reachable"`
+		}{Item: item.Name, Reachable: true})
+		return
+	}
+
+	recipes := make([]BlockedRecipe, 0)
+	for _, recipe := range graph.recipes[item.Name] {
+		blocked := BlockedRecipe{First: recipe.First, Second: recipe.Second, Unreachable: make([]string, 0)}
+		for _, ingredient := range []string{recipe.First, recipe.Second} {
+			if _, ok := graph.depth[ingredient]; !ok && !slices.Contains(blocked.Unreachable, ingredient) {
+				blocked.Unreachable = append(blocked.Unreachable, ingredient)
+			}
+		}
+		recipes = append(recipes, blocked)
+	}
+	neverMade, selfDependent := graph.unreachableCauses(item.Name)
+
+	json.NewEncoder(w).Encode(struct {
+		Item          string          `json:"item"`
+		Reachable     bool            `json:"reachable"`
+		Recipes       []BlockedRecipe `json:"recipes"`
+		NeverMade     []string        `json:"neverMade"`
+		SelfDependent []string        `json:"selfDependent"`
+	}{Item: item.Name, Recipes: recipes, NeverMade: neverMade, SelfDependent: selfDependent})
+}
+
+// buildCost rates how tedious an item is to craft: the depths of the
+// ingredients of its shallowest recipe plus the number of distinct items that
+// have to be crafted along the way, including the item itself.
+func (g *recipeGraph) buildCost(name string) (int, bool) {
+	steps, ok := g.shortestRecipe(name)
+	if !ok {
+		return 0, false
+	}
+	if isBaseElement(name) {
+		return 0, true
+	}
+	recipe := g.best[name]
+	return g.depth[recipe.First] + g.depth[recipe.Second] + len(steps), true
+}
+
+// storeItemMetrics writes the depth and build cost of every item into the
+// items table and returns the number of items updated.
+func storeItemMetrics(g *recipeGraph) (int, error) {
+	for _, column := range []string{"depth", "buildCost"} {
+		var exists int
+		err := db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('items') WHERE name = ?`, column).Scan(&exists)
+		if err != nil {
+			return 0, err
+		}
+		if exists == 0 {
+			if _, err := db.Exec(`ALTER TABLE items ADD COLUMN ` + column + ` INTEGER`); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if _, err := db.Exec(`CREATE INDEX IF NOT EXISTS idx_items_depth ON items(depth)`); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE items SET depth = NULL, buildCost = NULL`); err != nil {
+		return 0, err
+	}
+	stmt, err := tx.Prepare(`UPDATE items SET depth = ?, buildCost = ? WHERE name = ?`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	updated := 0
+	for name, depth := range g.depth {
+		cost, _ := g.buildCost(name)
+		if _, err := stmt.Exec(depth, cost, name); err != nil {
+			return 0, err
+		}
+		updated++
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	hasDepth.Store(true)
+	return updated, nil
+}
+
+// recipeKey identifies a build order independent of the order of ingredients
+// within each step.
+func recipeKey(steps []recipeStep) string {
+	var b strings.Builder
+	for _, step := range steps {
+		first, second := step.First, step.Second
+		if first > second {
+			first, second = second, first
+		}
+		fmt.Fprintf(&b, "%s+%s=%s\n", first, second, step.Result)
+	}
+	return b.String()
 }
@@ -0,0 +1,301 @@
+package main
+
+// Every .go file here is its own program, so the tests are run per program:
+//
+//	go test main.go emoji.go main_test.go
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testSchema is the schema the collector creates.
+const testSchema = `CREATE TABLE items (
+	name TEXT PRIMARY KEY,
+	emoji TEXT NOT NULL,
+	isNew BOOLEAN NOT NULL
+);
+CREATE TABLE combinations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	firstItem TEXT NOT NULL,
+	secondItem TEXT NOT NULL,
+	resultItem TEXT NOT NULL,
+	UNIQUE(firstItem, secondItem)
+)`
+
+// openTestDB points db at a new in-memory database created with schema. One
+// connection keeps every query on the same in-memory database.
+func openTestDB(t *testing.T, schema string) {
+	t.Helper()
+	testDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testDB.SetMaxOpenConns(1)
+	previous := db
+	db = testDB
+	t.Cleanup(func() {
+		db = previous
+		testDB.Close()
+	})
+
+	if _, err = db.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// useTestDB points db at a new in-memory database with the collector's
+// schema, the given items and the combinations in order.
+func useTestDB(t *testing.T, items []string, combinations []recipeStep) {
+	t.Helper()
+	openTestDB(t, testSchema)
+	for _, name := range items {
+		if _, err := db.Exec(`INSERT INTO items (name, emoji, isNew) VALUES (?, '🧪', 0)`, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range combinations {
+		if _, err := db.Exec(`INSERT INTO combinations (firstItem, secondItem, resultItem) VALUES (?, ?, ?)`, c.First, c.Second, c.Result); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// recipeTestGraph builds the graph of a small dataset. Rain has a deeper
+// recipe recorded before its shallowest one and is in a cycle with Mud.
+// Ghost, Phantom and Spirit only make each other, so they are an island
+// that can't be reached from the base elements.
+func recipeTestGraph(t *testing.T) *recipeGraph {
+	t.Helper()
+	useTestDB(t,
+		[]string{"Water", "Fire", "Wind", "Earth", "Steam", "Cloud", "Storm", "Rain", "Mud", "Ghost", "Phantom", "Spirit"},
+		[]recipeStep{
+			{"Water", "Fire", "Steam"},
+			{"Steam", "Wind", "Cloud"},
+			{"Cloud", "Wind", "Storm"},
+			{"Storm", "Water", "Rain"},
+			{"Cloud", "Water", "Rain"},
+			{"Rain", "Earth", "Mud"},
+			{"Mud", "Water", "Rain"},
+			{"Phantom", "Spirit", "Ghost"},
+			{"Ghost", "Spirit", "Phantom"},
+			{"Ghost", "Phantom", "Spirit"},
+		})
+	graph, err := buildRecipeGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return graph
+}
+
+func TestComputeDepths(t *testing.T) {
+	graph := recipeTestGraph(t)
+
+	want := map[string]int{
+		"Water": 0, "Fire": 0, "Wind": 0, "Earth": 0,
+		"Steam": 1, "Cloud": 2, "Storm": 3, "Rain": 3, "Mud": 4,
+	}
+	if !reflect.DeepEqual(graph.depth, want) {
+		t.Errorf("depths are %v, want %v", graph.depth, want)
+	}
+}
+
+func TestShortestRecipe(t *testing.T) {
+	graph := recipeTestGraph(t)
+
+	tests := []struct {
+		name  string
+		steps []recipeStep
+		ok    bool
+	}{
+		{"Rain", []recipeStep{{"Water", "Fire", "Steam"}, {"Steam", "Wind", "Cloud"}, {"Cloud", "Water", "Rain"}}, true},
+		{"Mud", []recipeStep{{"Water", "Fire", "Steam"}, {"Steam", "Wind", "Cloud"}, {"Cloud", "Water", "Rain"}, {"Rain", "Earth", "Mud"}}, true},
+		{"Water", []recipeStep{}, true},
+		{"Ghost", nil, false},
+		{"Unknown", nil, false},
+	}
+	for _, tt := range tests {
+		steps, ok := graph.shortestRecipe(tt.name)
+		if ok != tt.ok {
+			t.Errorf("shortestRecipe(%q) ok = %t, want %t", tt.name, ok, tt.ok)
+		}
+		if !reflect.DeepEqual(steps, tt.steps) {
+			t.Errorf("shortestRecipe(%q) = %v, want %v", tt.name, steps, tt.steps)
+		}
+	}
+}
+
+func TestHasRecipe(t *testing.T) {
+	graph := recipeTestGraph(t)
+
+	tests := []struct {
+		step recipeStep
+		want bool
+	}{
+		{recipeStep{"Water", "Fire", "Steam"}, true},
+		{recipeStep{"Fire", "Water", "Steam"}, true},
+		{recipeStep{"Water", "Fire", "Cloud"}, false},
+		{recipeStep{"Water", "Wind", "Steam"}, false},
+	}
+	for _, tt := range tests {
+		if got := graph.hasRecipe(tt.step); got != tt.want {
+			t.Errorf("hasRecipe(%v) = %t, want %t", tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestParseTemplatesNamesBrokenFile(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"good.html":   `{{define "good.html"}}{{emojiOr .Emoji}}{{end}}`,
+		"broken.html": `{{define "broken.html"}}{{if .Name}}unclosed{{end}}`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err := parseTemplates(filepath.Join(dir, "*.html"))
+	if err == nil {
+		t.Fatal("parsing a broken template succeeded")
+	}
+	if !strings.Contains(err.Error(), "broken.html") {
+		t.Errorf("error %q doesn't name broken.html", err)
+	}
+}
+
+func TestCheckSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{"collector schema", testSchema, ""},
+		{"items without isNew", `CREATE TABLE items (name TEXT PRIMARY KEY, emoji TEXT NOT NULL);
+CREATE TABLE combinations (id INTEGER PRIMARY KEY, firstItem TEXT, secondItem TEXT, resultItem TEXT)`, "table items has no column isNew"},
+		{"no combinations table", `CREATE TABLE items (name TEXT PRIMARY KEY, emoji TEXT NOT NULL, isNew BOOLEAN NOT NULL)`, "missing table combinations"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openTestDB(t, tt.schema)
+			err := checkSchema()
+			if tt.err == "" {
+				if err != nil {
+					t.Errorf("checkSchema() = %v, want no error", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("checkSchema() = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestStripTrailingSlash(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := stripTrailingSlash(next)
+
+	tests := []struct {
+		method, target string
+		code           int
+		location       string
+	}{
+		{"GET", "/", http.StatusOK, ""},
+		{"GET", "/search", http.StatusOK, ""},
+		{"GET", "/search/", http.StatusMovedPermanently, "/search"},
+		{"GET", "/search/?item=Fire", http.StatusMovedPermanently, "/search?item=Fire"},
+		{"GET", "/i/AC%2FDC%2F", http.StatusOK, ""},
+		{"GET", "/i/AC%2FDC%2F/", http.StatusMovedPermanently, "/i/AC%2FDC%2F"},
+		{"GET", "//evil.example/", http.StatusMovedPermanently, "/evil.example"},
+		{"GET", "///evil.example//", http.StatusMovedPermanently, "/evil.example"},
+		{"GET", "/\\evil.example/", http.StatusMovedPermanently, "/%5Cevil.example"},
+		{"POST", "/api/v1/chain/", http.StatusPermanentRedirect, "/api/v1/chain"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(tt.method, tt.target, nil))
+		if w.Code != tt.code {
+			t.Errorf("%s %s: status %d, want %d", tt.method, tt.target, w.Code, tt.code)
+		}
+		if location := w.Header().Get("Location"); location != tt.location {
+			t.Errorf("%s %s: Location %q, want %q", tt.method, tt.target, location, tt.location)
+		}
+	}
+}
+
+func TestSelfDependent(t *testing.T) {
+	useTestDB(t,
+		[]string{"Water", "Fire", "Wind", "Earth", "Rock", "Egg", "Chicken", "Ghost", "Phantom", "Spirit"},
+		[]recipeStep{
+			{"Rock", "Fire", "Rock"},
+			{"Chicken", "Water", "Egg"},
+			{"Egg", "Earth", "Chicken"},
+			{"Phantom", "Spirit", "Ghost"},
+			{"Ghost", "Spirit", "Phantom"},
+			{"Ghost", "Phantom", "Spirit"},
+		})
+	graph, err := buildRecipeGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SelfDependentItem{
+		{"Chicken", []recipeStep{{"Chicken", "Water", "Egg"}, {"Egg", "Earth", "Chicken"}}},
+		{"Egg", []recipeStep{{"Egg", "Earth", "Chicken"}, {"Chicken", "Water", "Egg"}}},
+		{"Rock", []recipeStep{{"Rock", "Fire", "Rock"}}},
+	}
+	if got := graph.selfDependent(); !reflect.DeepEqual(got, want) {
+		t.Errorf("selfDependent() = %v, want %v", got, want)
+	}
+}
+
+func TestUnreachableCauses(t *testing.T) {
+	// Unobtainium is used but never made, Chicken and Egg only make each other
+	// and Ghost, Phantom and Spirit are an island
+	useTestDB(t,
+		[]string{"Water", "Fire", "Wind", "Earth", "Plasma", "Chicken", "Egg", "Ghost", "Phantom", "Spirit", "Omelette"},
+		[]recipeStep{
+			{"Unobtainium", "Fire", "Plasma"},
+			{"Chicken", "Water", "Egg"},
+			{"Egg", "Earth", "Chicken"},
+			{"Phantom", "Spirit", "Ghost"},
+			{"Ghost", "Spirit", "Phantom"},
+			{"Ghost", "Phantom", "Spirit"},
+			{"Egg", "Plasma", "Omelette"},
+			{"Ghost", "Fire", "Omelette"},
+		})
+	graph, err := buildRecipeGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name                     string
+		neverMade, selfDependent []string
+	}{
+		{"Plasma", []string{"Unobtainium"}, []string{}},
+		{"Unobtainium", []string{"Unobtainium"}, []string{}},
+		{"Egg", []string{}, []string{"Chicken", "Egg"}},
+		{"Ghost", []string{}, []string{"Ghost", "Phantom", "Spirit"}},
+		{"Omelette", []string{"Unobtainium"}, []string{"Chicken", "Egg", "Ghost", "Phantom", "Spirit"}},
+	}
+	for _, tt := range tests {
+		neverMade, selfDependent := graph.unreachableCauses(tt.name)
+		if !reflect.DeepEqual(neverMade, tt.neverMade) {
+			t.Errorf("unreachableCauses(%q) never made %v, want %v", tt.name, neverMade, tt.neverMade)
+		}
+		if !reflect.DeepEqual(selfDependent, tt.selfDependent) {
+			t.Errorf("unreachableCauses(%q) self-dependent %v, want %v", tt.name, selfDependent, tt.selfDependent)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// mergeItems upserts the items of a shard the same way the collector does:
+// the emoji of the shard merged last wins, i.e. of the last one on the
+// command line, and isNew stays set once any shard saw a first discovery.
+// WHERE true keeps sqlite from reading ON CONFLICT as a join.
+const mergeItems = `INSERT INTO items (name, emoji, isNew)
+SELECT name, emoji, isNew FROM shard.items WHERE true
+ON CONFLICT(name) DO UPDATE SET emoji=excluded.emoji, isNew=(isNew OR excluded.isNew)`
+
+// mergeCombinations adds the pairs the target doesn't have yet in either
+// order, so shards that tried A + B and B + A don't both add the pair. Of the
+// two orders within a shard the one recorded first is kept. Both lookups use
+// the UNIQUE(firstItem, secondItem) index.
+const mergeCombinations = `INSERT INTO combinations (firstItem, secondItem, resultItem, createdAt)
+SELECT s.firstItem, s.secondItem, s.resultItem, s.createdAt FROM shard.combinations s
+WHERE NOT EXISTS (
+	SELECT 1 FROM main.combinations c WHERE c.firstItem = s.secondItem AND c.secondItem = s.firstItem
+) AND NOT EXISTS (
+	SELECT 1 FROM shard.combinations r WHERE r.firstItem = s.secondItem AND r.secondItem = s.firstItem AND r.id < s.id
+)
+ORDER BY s.id
+ON CONFLICT(firstItem, secondItem) DO NOTHING`
+
+func main() {
+	into := flag.String("into", "items.db", "database the shards are merged into, it must exist")
+	flag.Usage = func() {
+		fmt.Fprintln(flag.CommandLine.Output(), "Usage: mergeShards [-into items.db] items.shard-1.db...")
+		fmt.Fprintln(flag.CommandLine.Output(), "Merges the databases of collectors run with -shard <n>, e.g. go run mergeShards.go items.shard-*.db")
+		fmt.Fprintln(flag.CommandLine.Output(), "Shards are merged in order, an item's emoji is taken from the last one and isNew is kept if any shard")
+		fmt.Fprintln(flag.CommandLine.Output(), "set it. A pair recorded in both orders is only added once, in the order merged first.")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if _, err := os.Stat(*into); err != nil {
+		log.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", *into)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	// ATTACH only applies to the connection it ran on
+	db.SetMaxOpenConns(1)
+
+	// Shards always have createdAt, the collector adds it on start
+	var hasCreatedAt bool
+	if err = db.QueryRow("SELECT COUNT(*) > 0 FROM pragma_table_info('combinations') WHERE name = 'createdAt'").Scan(&hasCreatedAt); err != nil {
+		log.Fatal(err)
+	}
+	if !hasCreatedAt {
+		if _, err = db.Exec("ALTER TABLE combinations ADD COLUMN createdAt TIMESTAMP"); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	for _, path := range flag.Args() {
+		if _, err = os.Stat(path); err != nil {
+			log.Fatal(err)
+		}
+		if _, err = db.Exec("ATTACH DATABASE ? AS shard", "file:"+path+"?mode=ro"); err != nil {
+			log.Fatal(err)
+		}
+		items, combinations := mergeShard(db)
+		if _, err = db.Exec("DETACH DATABASE shard"); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s: merged %d items and %d new combinations\n", path, items, combinations)
+	}
+}
+
+// mergeShard copies the attached shard into the main database in one
+// transaction and returns the number of items and combinations written.
+func mergeShard(db *sql.DB) (int64, int64) {
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(mergeItems)
+	if err != nil {
+		log.Fatal(err)
+	}
+	items, _ := res.RowsAffected()
+	res, err = tx.Exec(mergeCombinations)
+	if err != nil {
+		log.Fatal(err)
+	}
+	combinations, _ := res.RowsAffected()
+
+	if err = tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+	return items, combinations
+}
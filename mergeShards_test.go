@@ -0,0 +1,163 @@
+package main
+
+// Every .go file here is its own program, so the tests are run per program:
+//
+//	go test mergeShards.go mergeShards_test.go
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// shardSchema is the schema the collector creates, createdAt included.
+const shardSchema = `CREATE TABLE items (
+	name TEXT PRIMARY KEY,
+	emoji TEXT NOT NULL,
+	isNew BOOLEAN NOT NULL
+);
+CREATE TABLE combinations (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	firstItem TEXT NOT NULL,
+	secondItem TEXT NOT NULL,
+	resultItem TEXT NOT NULL,
+	createdAt TIMESTAMP,
+	UNIQUE(firstItem, secondItem)
+)`
+
+type shardItem struct {
+	name, emoji string
+	isNew       bool
+}
+
+// createShard writes a database at path with the given items and the
+// combinations, first, second and result each, in order.
+func createShard(t *testing.T, path string, items []shardItem, combinations [][3]string) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err = db.Exec(shardSchema); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range items {
+		if _, err = db.Exec(`INSERT INTO items (name, emoji, isNew) VALUES (?, ?, ?)`, item.name, item.emoji, item.isNew); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, c := range combinations {
+		if _, err = db.Exec(`INSERT INTO combinations (firstItem, secondItem, resultItem, createdAt) VALUES (?, ?, ?, CURRENT_TIMESTAMP)`, c[0], c[1], c[2]); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// mergeTestShards merges the shards into an empty database in order, the same
+// way main does, and returns it.
+func mergeTestShards(t *testing.T, shards ...string) *sql.DB {
+	t.Helper()
+	into := filepath.Join(t.TempDir(), "items.db")
+	createShard(t, into, nil, nil)
+	db, err := sql.Open("sqlite3", into)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	for _, path := range shards {
+		if _, err = db.Exec("ATTACH DATABASE ? AS shard", "file:"+path+"?mode=ro"); err != nil {
+			t.Fatal(err)
+		}
+		mergeShard(db)
+		if _, err = db.Exec("DETACH DATABASE shard"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func mergedCombinations(t *testing.T, db *sql.DB) [][3]string {
+	t.Helper()
+	rows, err := db.Query(`SELECT firstItem, secondItem, resultItem FROM combinations ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	var combinations [][3]string
+	for rows.Next() {
+		var c [3]string
+		if err := rows.Scan(&c[0], &c[1], &c[2]); err != nil {
+			t.Fatal(err)
+		}
+		combinations = append(combinations, c)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return combinations
+}
+
+func TestMergeShardsKeepsOneOrderOfEachPair(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "items.shard-1.db"), filepath.Join(dir, "items.shard-2.db")
+	// The second shard tried Fire + Water, which the first has as Water + Fire,
+	// and recorded both orders of Wind + Earth itself
+	createShard(t, first, nil, [][3]string{
+		{"Water", "Fire", "Steam"},
+	})
+	createShard(t, second, nil, [][3]string{
+		{"Fire", "Water", "Mist"},
+		{"Wind", "Earth", "Dust"},
+		{"Earth", "Wind", "Sand"},
+		{"Water", "Water", "Lake"},
+	})
+
+	db := mergeTestShards(t, first, second)
+
+	want := [][3]string{
+		{"Water", "Fire", "Steam"},
+		{"Wind", "Earth", "Dust"},
+		{"Water", "Water", "Lake"},
+	}
+	if got := mergedCombinations(t, db); !reflect.DeepEqual(got, want) {
+		t.Errorf("merged combinations %v, want %v", got, want)
+	}
+}
+
+func TestMergeShardsOrsIsNew(t *testing.T) {
+	dir := t.TempDir()
+	first, second := filepath.Join(dir, "items.shard-1.db"), filepath.Join(dir, "items.shard-2.db")
+	createShard(t, first, []shardItem{{"Steam", "💨", true}, {"Mud", "🟤", false}}, nil)
+	createShard(t, second, []shardItem{{"Steam", "♨️", false}, {"Mud", "💩", false}}, nil)
+
+	db := mergeTestShards(t, first, second)
+
+	rows, err := db.Query(`SELECT name, emoji, isNew FROM items ORDER BY name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []shardItem
+	for rows.Next() {
+		var item shardItem
+		if err := rows.Scan(&item.name, &item.emoji, &item.isNew); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, item)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// isNew stays set from the first shard, the emoji is the last shard's
+	want := []shardItem{{"Mud", "💩", false}, {"Steam", "♨️", true}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("merged items %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// normalizeName trims an item name and collapses inner runs of whitespace.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report the duplicates that would be merged")
+	flag.Parse()
+
+	db, err := sql.Open("sqlite3", "items.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Old names of merged items keep resolving through the aliases table
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS aliases (
+		alias TEXT PRIMARY KEY,
+		canonical TEXT NOT NULL,
+		FOREIGN KEY (canonical) REFERENCES items(name)
+	)`)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, err := db.Query("SELECT name FROM items")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	merges := make(map[string]string)
+	for rows.Next() {
+		var name string
+		if err = rows.Scan(&name); err != nil {
+			log.Fatal(err)
+		}
+		if canonical := normalizeName(name); canonical != name {
+			merges[name] = canonical
+		}
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	for name, canonical := range merges {
+		fmt.Printf("%q -> %q\n", name, canonical)
+	}
+	if *dryRun || len(merges) == 0 {
+		fmt.Printf("Found %d items to merge\n", len(merges))
+		return
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for name, canonical := range merges {
+		mergeItem(tx, name, canonical)
+	}
+	if err = tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Merged %d items\n", len(merges))
+}
+
+// mergeItem moves everything referencing name over to canonical, creating
+// the canonical item if needed, and records name as an alias.
+func mergeItem(tx *sql.Tx, name, canonical string) {
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		// Keep the emoji of an existing canonical item, but keep isNew if
+		// either of them was a first discovery
+		{`INSERT INTO items (name, emoji, isNew) SELECT ?, emoji, isNew FROM items WHERE name = ?
+			ON CONFLICT(name) DO UPDATE SET isNew = (isNew OR excluded.isNew)`, []interface{}{canonical, name}},
+		// Pairs that already exist for the canonical name are left behind
+		// by OR IGNORE and deleted below
+		{`UPDATE OR IGNORE combinations SET firstItem = ? WHERE firstItem = ?`, []interface{}{canonical, name}},
+		{`UPDATE OR IGNORE combinations SET secondItem = ? WHERE secondItem = ?`, []interface{}{canonical, name}},
+		{`DELETE FROM combinations WHERE firstItem = ? OR secondItem = ?`, []interface{}{name, name}},
+		{`UPDATE combinations SET resultItem = ? WHERE resultItem = ?`, []interface{}{canonical, name}},
+		{`UPDATE aliases SET canonical = ? WHERE canonical = ?`, []interface{}{canonical, name}},
+		{`DELETE FROM items WHERE name = ?`, []interface{}{name}},
+		{`INSERT OR REPLACE INTO aliases (alias, canonical) VALUES (?, ?)`, []interface{}{name, canonical}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+			log.Fatalf("Failed to merge %q into %q: %v", name, canonical, err)
+		}
+	}
+}
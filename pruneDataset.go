@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type combination struct {
+	id int64
+	recipe
+}
+
+func main() {
+	out := flag.String("out", "items.pruned.db", "write the pruned dataset to this new file, items.db is left untouched")
+	unreachable := flag.Bool("unreachable", true, "remove items and combinations that can't be crafted from the base elements")
+	keepRecipes := flag.Int("keep-recipes", 0, "keep at most this many reachable recipes per item, shallowest first (0 keeps all)")
+	flag.Parse()
+
+	if _, err := os.Stat(*out); err == nil {
+		log.Fatalf("%s already exists", *out)
+	}
+
+	src, err := sql.Open("sqlite3", "items.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	_, err = src.Exec("VACUUM INTO ?", *out)
+	src.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	db, err := sql.Open("sqlite3", *out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	itemsBefore, combinationsBefore := countRows(db)
+
+	rows, err := db.Query("SELECT id, firstItem, secondItem, resultItem FROM combinations ORDER BY id")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var combinations []combination
+	for rows.Next() {
+		var c combination
+		if err = rows.Scan(&c.id, &c.first, &c.second, &c.result); err != nil {
+			log.Fatal(err)
+		}
+		combinations = append(combinations, c)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	recipes := make([]recipe, len(combinations))
+	for i, c := range combinations {
+		recipes[i] = c.recipe
+	}
+	depth, _ := computeDepths(recipes)
+
+	remove := make(map[int64]bool)
+	var reachable []combination
+	for _, c := range combinations {
+		if _, ok := recipeDepth(depth, c.recipe); ok {
+			reachable = append(reachable, c)
+		} else if *unreachable {
+			remove[c.id] = true
+		}
+	}
+
+	// Keeping recipes shallowest first keeps every kept item at its depth,
+	// so the pruned dataset stays reachable from the base elements
+	if *keepRecipes > 0 {
+		sort.SliceStable(reachable, func(i, j int) bool {
+			di, _ := recipeDepth(depth, reachable[i].recipe)
+			dj, _ := recipeDepth(depth, reachable[j].recipe)
+			return di < dj
+		})
+		kept := make(map[string]int)
+		for _, c := range reachable {
+			if kept[c.result] >= *keepRecipes {
+				remove[c.id] = true
+			}
+			kept[c.result]++
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	stmt, err := tx.Prepare("DELETE FROM combinations WHERE id = ?")
+	if err != nil {
+		log.Fatal(err)
+	}
+	for id := range remove {
+		if _, err = stmt.Exec(id); err != nil {
+			log.Fatal(err)
+		}
+	}
+	stmt.Close()
+	if *unreachable {
+		// Unreachable items are exactly the ones without a depth
+		if _, err = tx.Exec("CREATE TEMP TABLE reachable (name TEXT PRIMARY KEY)"); err != nil {
+			log.Fatal(err)
+		}
+		for name := range depth {
+			if _, err = tx.Exec("INSERT INTO reachable (name) VALUES (?)", name); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if _, err = tx.Exec("DELETE FROM items WHERE name NOT IN (SELECT name FROM reachable)"); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err = db.Exec("VACUUM"); err != nil {
+		log.Fatal(err)
+	}
+
+	itemsAfter, combinationsAfter := countRows(db)
+	fmt.Printf("Items: %d -> %d\n", itemsBefore, itemsAfter)
+	fmt.Printf("Combinations: %d -> %d\n", combinationsBefore, combinationsAfter)
+	fmt.Printf("Pruned dataset saved to %s\n", *out)
+}
+
+func countRows(db *sql.DB) (items, combinations int) {
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&items); err != nil {
+		log.Fatal(err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM combinations").Scan(&combinations); err != nil {
+		log.Fatal(err)
+	}
+	return items, combinations
+}
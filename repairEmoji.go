@@ -0,0 +1,86 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"unicode/utf8"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// undoDoubleDecode reverses UTF-8 bytes that were decoded as Latin-1 and
+// encoded to UTF-8 again. ok is false if s can't be such a string.
+func undoDoubleDecode(s string) (string, bool) {
+	raw := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xFF {
+			return "", false
+		}
+		raw = append(raw, byte(r))
+	}
+	if !utf8.Valid(raw) {
+		return "", false
+	}
+	return string(raw), true
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "only report what would be repaired")
+	flag.Parse()
+
+	db, err := sql.Open("sqlite3", "items.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT name, emoji FROM items")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	repairs := make(map[string]string)
+	unchanged := 0
+	for rows.Next() {
+		var name, emoji string
+		if err = rows.Scan(&name, &emoji); err != nil {
+			log.Fatal(err)
+		}
+		if isEmoji(emoji) {
+			unchanged++
+			continue
+		}
+		fixed, ok := undoDoubleDecode(emoji)
+		if !ok || !isEmoji(fixed) {
+			fmt.Printf("Can't repair %s: %q\n", name, emoji)
+			unchanged++
+			continue
+		}
+		fmt.Printf("Repairing %s: %q -> %s\n", name, emoji, fixed)
+		repairs[name] = fixed
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	if !*dryRun {
+		tx, err := db.Begin()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for name, emoji := range repairs {
+			if _, err = tx.Exec("UPDATE items SET emoji = ? WHERE name = ?", emoji, name); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if err = tx.Commit(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fmt.Printf("Repaired %d emoji, %d left unchanged\n", len(repairs), unchanged)
+}
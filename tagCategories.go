@@ -0,0 +1,131 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type categoryRule struct {
+	Category string
+	Keywords []string
+	Emojis   []string
+}
+
+// categoryRules are checked in order, the first rule with a matching keyword
+// in the item name or a matching emoji wins. Extend these to improve tagging.
+var categoryRules = []categoryRule{
+	{
+		Category: "mythical",
+		Keywords: []string{"dragon", "unicorn", "phoenix", "wizard", "magic", "ghost", "vampire", "zombie", "god", "angel", "demon", "fairy", "mermaid", "giant", "titan", "spell"},
+		Emojis:   []string{"🐉", "🦄", "🧙", "👻", "🧛", "🧟", "😇", "😈", "🧚", "🧜", "✨", "🔮"},
+	},
+	{
+		Category: "animal",
+		Keywords: []string{"dog", "cat", "fish", "bird", "horse", "cow", "pig", "lion", "tiger", "bear", "wolf", "snake", "shark", "whale", "frog", "bee", "ant", "spider", "monkey", "duck", "owl", "mouse"},
+		Emojis:   []string{"🐶", "🐱", "🐟", "🐦", "🐴", "🐮", "🐷", "🦁", "🐯", "🐻", "🐺", "🐍", "🦈", "🐳", "🐸", "🐝", "🐜", "🕷️", "🐒", "🦆", "🦉", "🐭"},
+	},
+	{
+		Category: "food",
+		Keywords: []string{"bread", "cake", "pizza", "soup", "cheese", "wine", "beer", "tea", "coffee", "fruit", "apple", "banana", "sugar", "salt", "cookie", "meat", "rice", "pie", "sandwich", "chocolate"},
+		Emojis:   []string{"🍞", "🍰", "🍕", "🍲", "🧀", "🍷", "🍺", "🍵", "☕", "🍎", "🍌", "🍬", "🧂", "🍪", "🍖", "🍚", "🥧", "🥪", "🍫"},
+	},
+	{
+		Category: "place",
+		Keywords: []string{"city", "island", "mountain", "volcano", "desert", "forest", "ocean", "lake", "river", "castle", "house", "village", "planet", "country", "beach", "cave", "jungle"},
+		Emojis:   []string{"🏙️", "🏝️", "⛰️", "🌋", "🏜️", "🌲", "🌊", "🏰", "🏠", "🏡", "🪐", "🏖️", "🌴"},
+	},
+	{
+		Category: "tech",
+		Keywords: []string{"computer", "robot", "phone", "internet", "car", "rocket", "engine", "machine", "electric", "laser", "battery", "tv", "radio", "ai", "cyborg", "satellite"},
+		Emojis:   []string{"💻", "🤖", "📱", "🌐", "🚗", "🚀", "⚙️", "⚡", "🔋", "📺", "📻", "🛰️"},
+	},
+}
+
+func categorize(name, emoji string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == ' ' || r == '-'
+	})
+	for _, rule := range categoryRules {
+		for _, e := range rule.Emojis {
+			if emoji == e {
+				return rule.Category
+			}
+		}
+		for _, keyword := range rule.Keywords {
+			for _, word := range words {
+				if word == keyword || word == keyword+"s" {
+					return rule.Category
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func main() {
+	db, err := sql.Open("sqlite3", "items.db")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	// Add the category column on databases that don't have it yet
+	var hasCategory int
+	err = db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('items') WHERE name = 'category'`).Scan(&hasCategory)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if hasCategory == 0 {
+		if _, err = db.Exec(`ALTER TABLE items ADD COLUMN category TEXT NOT NULL DEFAULT ''`); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	rows, err := db.Query("SELECT name, emoji FROM items")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	categories := make(map[string]string)
+	for rows.Next() {
+		var name, emoji string
+		if err = rows.Scan(&name, &emoji); err != nil {
+			log.Fatal(err)
+		}
+		categories[name] = categorize(name, emoji)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+
+	// Write all categories in one transaction, tagging is rerun from scratch
+	tx, err := db.Begin()
+	if err != nil {
+		log.Fatal(err)
+	}
+	stmt, err := tx.Prepare("UPDATE items SET category = ? WHERE name = ?")
+	if err != nil {
+		log.Fatal(err)
+	}
+	tagged := 0
+	for name, category := range categories {
+		if _, err = stmt.Exec(category, name); err != nil {
+			log.Fatal(err)
+		}
+		if category != "" {
+			tagged++
+		}
+	}
+	stmt.Close()
+	if err = tx.Commit(); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("Tagged %d of %d items with a category", tagged, len(categories))
+}
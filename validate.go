@@ -0,0 +1,206 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// check is the outcome of one data quality check. Critical checks make the
+// command exit with a non-zero status when they find anything.
+type check struct {
+	Name     string   `json:"name"`
+	Critical bool     `json:"critical"`
+	Count    int      `json:"count"`
+	Samples  []string `json:"samples"`
+}
+
+type report struct {
+	Checks []*check `json:"checks"`
+	Passed bool     `json:"passed"`
+}
+
+// maxSamples is how many examples each check keeps.
+var maxSamples int
+
+func (c *check) add(sample string) {
+	c.Count++
+	if len(c.Samples) < maxSamples {
+		c.Samples = append(c.Samples, sample)
+	}
+}
+
+func main() {
+	asJSON := flag.Bool("json", false, "print the report as JSON instead of text")
+	flag.IntVar(&maxSamples, "samples", 5, "number of examples listed for each issue")
+	flag.Parse()
+
+	db, err := sql.Open("sqlite3", "file:items.db?mode=ro")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	orphans := &check{Name: "orphan combinations", Critical: true, Samples: make([]string, 0)}
+	queryCheck(db, orphans, `SELECT '#' || id || ': ' || firstItem || ' + ' || secondItem || ' = ' || resultItem
+FROM combinations
+WHERE firstItem NOT IN (SELECT name FROM items)
+	OR secondItem NOT IN (SELECT name FROM items)
+	OR resultItem NOT IN (SELECT name FROM items)
+ORDER BY id`)
+
+	// Same query as findAsymmetric.go
+	asymmetric := &check{Name: "asymmetric pairs", Samples: make([]string, 0)}
+	queryCheck(db, asymmetric, `SELECT a.firstItem || ' + ' || a.secondItem || ' = ' || a.resultItem || ', reversed = ' || b.resultItem
+FROM combinations a
+JOIN combinations b ON b.firstItem = a.secondItem AND b.secondItem = a.firstItem
+WHERE a.firstItem < a.secondItem AND a.resultItem != b.resultItem
+ORDER BY a.firstItem, a.secondItem`)
+
+	unreachable := &check{Name: "unreachable items", Samples: make([]string, 0)}
+	invalidEmoji := &check{Name: "invalid emoji", Samples: make([]string, 0)}
+	whitespace := &check{Name: "whitespace in names", Samples: make([]string, 0)}
+	duplicates := &check{Name: "whitespace duplicates", Critical: true, Samples: make([]string, 0)}
+
+	names, emoji := loadItems(db)
+	depth, _ := computeDepths(loadRecipes(db))
+	known := make(map[string]bool)
+	for _, name := range names {
+		known[name] = true
+	}
+	for _, name := range names {
+		if _, ok := depth[name]; !ok {
+			unreachable.add(name)
+		}
+		if !isEmoji(emoji[name]) {
+			invalidEmoji.add(fmt.Sprintf("%s: %q", name, emoji[name]))
+		}
+		if canonical := normalizeName(name); canonical != name {
+			whitespace.add(fmt.Sprintf("%q", name))
+			// mergeWhitespace.go can merge these, but until then the
+			// same item is listed twice
+			if known[canonical] {
+				duplicates.add(fmt.Sprintf("%q and %q", name, canonical))
+			}
+		}
+	}
+
+	r := report{
+		Checks: []*check{orphans, duplicates, asymmetric, unreachable, invalidEmoji, whitespace},
+		Passed: true,
+	}
+	for _, c := range r.Checks {
+		if c.Critical && c.Count > 0 {
+			r.Passed = false
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(r); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		for _, c := range r.Checks {
+			severity := "warning"
+			if c.Critical {
+				severity = "critical"
+			}
+			fmt.Printf("%s (%s): %d\n", c.Name, severity, c.Count)
+			for _, sample := range c.Samples {
+				fmt.Printf("  %s\n", sample)
+			}
+			if c.Count > len(c.Samples) {
+				fmt.Printf("  ... and %d more\n", c.Count-len(c.Samples))
+			}
+		}
+		if r.Passed {
+			fmt.Println("\nNo critical issues found")
+		} else {
+			fmt.Println("\nCritical issues found")
+		}
+	}
+
+	if !r.Passed {
+		os.Exit(1)
+	}
+}
+
+// queryCheck adds every row of a query returning one text column to c.
+func queryCheck(db *sql.DB, c *check, query string) {
+	rows, err := db.Query(query)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for rows.Next() {
+		var sample string
+		if err = rows.Scan(&sample); err != nil {
+			log.Fatal(err)
+		}
+		c.add(sample)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// loadItems returns the item names in order and the emoji of each.
+func loadItems(db *sql.DB) ([]string, map[string]string) {
+	rows, err := db.Query("SELECT name, emoji FROM items ORDER BY name")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var names []string
+	emoji := make(map[string]string)
+	for rows.Next() {
+		var name, e string
+		if err = rows.Scan(&name, &e); err != nil {
+			log.Fatal(err)
+		}
+		names = append(names, name)
+		emoji[name] = e
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return names, emoji
+}
+
+// loadRecipes returns every combination as a recipe.
+func loadRecipes(db *sql.DB) []recipe {
+	rows, err := db.Query("SELECT firstItem, secondItem, resultItem FROM combinations")
+	if err != nil {
+		log.Fatal(err)
+	}
+	var recipes []recipe
+	for rows.Next() {
+		var r recipe
+		if err = rows.Scan(&r.first, &r.second, &r.result); err != nil {
+			log.Fatal(err)
+		}
+		recipes = append(recipes, r)
+	}
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return recipes
+}
+
+// normalizeName trims an item name and collapses inner runs of whitespace,
+// see mergeWhitespace.go.
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}